@@ -0,0 +1,115 @@
+// Package api содержит спецификацию OpenAPI HTTP API сервиса.
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPI спецификация OpenAPI 3.1 в YAML. Пути в ней указаны относительно префикса /api/v1.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+var (
+	specJSON     []byte
+	specJSONErr  error
+	specJSONOnce sync.Once
+)
+
+// OpenAPIJSON возвращает спецификацию OpenAPI в JSON. Преобразование выполняется один раз.
+func OpenAPIJSON() ([]byte, error) {
+	specJSONOnce.Do(func() {
+		var document interface{}
+		if specJSONErr = yaml.Unmarshal(OpenAPI, &document); specJSONErr != nil {
+			return
+		}
+		specJSON, specJSONErr = json.Marshal(jsonValue(document))
+	})
+	return specJSON, specJSONErr
+}
+
+// jsonValue заменяет в документе YAML словари с нестроковыми ключами словарями со строковыми ключами,
+// которые можно записать в JSON.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonValue(item)
+		}
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonValue(item)
+		}
+	}
+	return value
+}
+
+// Load разбирает спецификацию OpenAPI и проверяет ее корректность.
+// kin-openapi проверяет схемы по правилам OpenAPI 3.0, поэтому он получает копию спецификации,
+// в которой типы вида [T, "null"] из OpenAPI 3.1 заменены на type: T и nullable: true.
+// Опубликованная спецификация при этом не меняется.
+func Load(ctx context.Context) (*openapi3.T, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(OpenAPI, &document); err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI specification: %w", err)
+	}
+	data, err := json.Marshal(nullableValue(jsonValue(document)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI specification: %w", err)
+	}
+	spec, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI specification: %w", err)
+	}
+	if err := spec.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI specification: %w", err)
+	}
+	return spec, nil
+}
+
+// nullableValue заменяет в документе типы вида [T, "null"] на type: T и nullable: true.
+func nullableValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = nullableValue(item)
+		}
+		if types, ok := v["type"].([]interface{}); ok {
+			var rest []interface{}
+			for _, typ := range types {
+				if typ != "null" {
+					rest = append(rest, typ)
+				}
+			}
+			if len(rest) < len(types) {
+				v["nullable"] = true
+				switch len(rest) {
+				case 0:
+					delete(v, "type")
+				case 1:
+					v["type"] = rest[0]
+				default:
+					v["type"] = rest
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = nullableValue(item)
+		}
+	}
+	return value
+}
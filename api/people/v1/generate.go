@@ -0,0 +1,4 @@
+// Package peoplev1 содержит gRPC API людей, сгенерированный по people.proto.
+package peoplev1
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative api/people/v1/people.proto
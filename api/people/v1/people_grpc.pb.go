@@ -0,0 +1,334 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: api/people/v1/people.proto
+
+package peoplev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PeopleService_CreatePerson_FullMethodName = "/people.v1.PeopleService/CreatePerson"
+	PeopleService_GetPerson_FullMethodName    = "/people.v1.PeopleService/GetPerson"
+	PeopleService_ListPeople_FullMethodName   = "/people.v1.PeopleService/ListPeople"
+	PeopleService_UpdatePerson_FullMethodName = "/people.v1.PeopleService/UpdatePerson"
+	PeopleService_DeletePerson_FullMethodName = "/people.v1.PeopleService/DeletePerson"
+	PeopleService_WatchPeople_FullMethodName  = "/people.v1.PeopleService/WatchPeople"
+)
+
+// PeopleServiceClient is the client API for PeopleService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PeopleServiceClient interface {
+	// CreatePerson создает человека и обогащает его возрастом, полом и национальностью.
+	CreatePerson(ctx context.Context, in *CreatePersonRequest, opts ...grpc.CallOption) (*Person, error)
+	// GetPerson возвращает человека по идентификатору.
+	GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error)
+	// ListPeople возвращает страницу списка людей с фильтрами и сортировкой.
+	ListPeople(ctx context.Context, in *ListPeopleRequest, opts ...grpc.CallOption) (*ListPeopleResponse, error)
+	// UpdatePerson заменяет данные человека.
+	UpdatePerson(ctx context.Context, in *UpdatePersonRequest, opts ...grpc.CallOption) (*Person, error)
+	// DeletePerson удаляет человека.
+	DeletePerson(ctx context.Context, in *DeletePersonRequest, opts ...grpc.CallOption) (*DeletePersonResponse, error)
+	// WatchPeople отправляет изменения людей, выполненные после начала вызова.
+	WatchPeople(ctx context.Context, in *WatchPeopleRequest, opts ...grpc.CallOption) (PeopleService_WatchPeopleClient, error)
+}
+
+type peopleServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPeopleServiceClient(cc grpc.ClientConnInterface) PeopleServiceClient {
+	return &peopleServiceClient{cc}
+}
+
+func (c *peopleServiceClient) CreatePerson(ctx context.Context, in *CreatePersonRequest, opts ...grpc.CallOption) (*Person, error) {
+	out := new(Person)
+	err := c.cc.Invoke(ctx, PeopleService_CreatePerson_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleServiceClient) GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error) {
+	out := new(Person)
+	err := c.cc.Invoke(ctx, PeopleService_GetPerson_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleServiceClient) ListPeople(ctx context.Context, in *ListPeopleRequest, opts ...grpc.CallOption) (*ListPeopleResponse, error) {
+	out := new(ListPeopleResponse)
+	err := c.cc.Invoke(ctx, PeopleService_ListPeople_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleServiceClient) UpdatePerson(ctx context.Context, in *UpdatePersonRequest, opts ...grpc.CallOption) (*Person, error) {
+	out := new(Person)
+	err := c.cc.Invoke(ctx, PeopleService_UpdatePerson_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleServiceClient) DeletePerson(ctx context.Context, in *DeletePersonRequest, opts ...grpc.CallOption) (*DeletePersonResponse, error) {
+	out := new(DeletePersonResponse)
+	err := c.cc.Invoke(ctx, PeopleService_DeletePerson_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleServiceClient) WatchPeople(ctx context.Context, in *WatchPeopleRequest, opts ...grpc.CallOption) (PeopleService_WatchPeopleClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PeopleService_ServiceDesc.Streams[0], PeopleService_WatchPeople_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &peopleServiceWatchPeopleClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PeopleService_WatchPeopleClient interface {
+	Recv() (*PersonEvent, error)
+	grpc.ClientStream
+}
+
+type peopleServiceWatchPeopleClient struct {
+	grpc.ClientStream
+}
+
+func (x *peopleServiceWatchPeopleClient) Recv() (*PersonEvent, error) {
+	m := new(PersonEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PeopleServiceServer is the server API for PeopleService service.
+// All implementations must embed UnimplementedPeopleServiceServer
+// for forward compatibility
+type PeopleServiceServer interface {
+	// CreatePerson создает человека и обогащает его возрастом, полом и национальностью.
+	CreatePerson(context.Context, *CreatePersonRequest) (*Person, error)
+	// GetPerson возвращает человека по идентификатору.
+	GetPerson(context.Context, *GetPersonRequest) (*Person, error)
+	// ListPeople возвращает страницу списка людей с фильтрами и сортировкой.
+	ListPeople(context.Context, *ListPeopleRequest) (*ListPeopleResponse, error)
+	// UpdatePerson заменяет данные человека.
+	UpdatePerson(context.Context, *UpdatePersonRequest) (*Person, error)
+	// DeletePerson удаляет человека.
+	DeletePerson(context.Context, *DeletePersonRequest) (*DeletePersonResponse, error)
+	// WatchPeople отправляет изменения людей, выполненные после начала вызова.
+	WatchPeople(*WatchPeopleRequest, PeopleService_WatchPeopleServer) error
+	mustEmbedUnimplementedPeopleServiceServer()
+}
+
+// UnimplementedPeopleServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPeopleServiceServer struct {
+}
+
+func (UnimplementedPeopleServiceServer) CreatePerson(context.Context, *CreatePersonRequest) (*Person, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePerson not implemented")
+}
+func (UnimplementedPeopleServiceServer) GetPerson(context.Context, *GetPersonRequest) (*Person, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPerson not implemented")
+}
+func (UnimplementedPeopleServiceServer) ListPeople(context.Context, *ListPeopleRequest) (*ListPeopleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPeople not implemented")
+}
+func (UnimplementedPeopleServiceServer) UpdatePerson(context.Context, *UpdatePersonRequest) (*Person, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePerson not implemented")
+}
+func (UnimplementedPeopleServiceServer) DeletePerson(context.Context, *DeletePersonRequest) (*DeletePersonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePerson not implemented")
+}
+func (UnimplementedPeopleServiceServer) WatchPeople(*WatchPeopleRequest, PeopleService_WatchPeopleServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPeople not implemented")
+}
+func (UnimplementedPeopleServiceServer) mustEmbedUnimplementedPeopleServiceServer() {}
+
+// UnsafePeopleServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PeopleServiceServer will
+// result in compilation errors.
+type UnsafePeopleServiceServer interface {
+	mustEmbedUnimplementedPeopleServiceServer()
+}
+
+func RegisterPeopleServiceServer(s grpc.ServiceRegistrar, srv PeopleServiceServer) {
+	s.RegisterService(&PeopleService_ServiceDesc, srv)
+}
+
+func _PeopleService_CreatePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServiceServer).CreatePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeopleService_CreatePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServiceServer).CreatePerson(ctx, req.(*CreatePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeopleService_GetPerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServiceServer).GetPerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeopleService_GetPerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServiceServer).GetPerson(ctx, req.(*GetPersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeopleService_ListPeople_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPeopleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServiceServer).ListPeople(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeopleService_ListPeople_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServiceServer).ListPeople(ctx, req.(*ListPeopleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeopleService_UpdatePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServiceServer).UpdatePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeopleService_UpdatePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServiceServer).UpdatePerson(ctx, req.(*UpdatePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeopleService_DeletePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServiceServer).DeletePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PeopleService_DeletePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServiceServer).DeletePerson(ctx, req.(*DeletePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeopleService_WatchPeople_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPeopleRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PeopleServiceServer).WatchPeople(m, &peopleServiceWatchPeopleServer{stream})
+}
+
+type PeopleService_WatchPeopleServer interface {
+	Send(*PersonEvent) error
+	grpc.ServerStream
+}
+
+type peopleServiceWatchPeopleServer struct {
+	grpc.ServerStream
+}
+
+func (x *peopleServiceWatchPeopleServer) Send(m *PersonEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// PeopleService_ServiceDesc is the grpc.ServiceDesc for PeopleService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PeopleService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "people.v1.PeopleService",
+	HandlerType: (*PeopleServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreatePerson",
+			Handler:    _PeopleService_CreatePerson_Handler,
+		},
+		{
+			MethodName: "GetPerson",
+			Handler:    _PeopleService_GetPerson_Handler,
+		},
+		{
+			MethodName: "ListPeople",
+			Handler:    _PeopleService_ListPeople_Handler,
+		},
+		{
+			MethodName: "UpdatePerson",
+			Handler:    _PeopleService_UpdatePerson_Handler,
+		},
+		{
+			MethodName: "DeletePerson",
+			Handler:    _PeopleService_DeletePerson_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPeople",
+			Handler:       _PeopleService_WatchPeople_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/people/v1/people.proto",
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testProject/internal/config"
+	"testProject/internal/importer"
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/service"
+)
+
+const importUsage = "usage: import [-batch N] [-enrich] [-map COLUMN=field,...] FILE.csv|FILE.ndjson|FILE.xlsx"
+
+// runImport загружает людей из файла CSV, NDJSON или XLSX пачками по -batch строк.
+// Некорректные строки и строки, которые отклонила база данных, пропускаются и выводятся в stderr
+// с номером строки данных.
+func runImport(cfg *config.Config, logger *logging.Logger, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	batchSize := flags.Int("batch", 10000, "rows per transaction")
+	enrich := flags.Bool("enrich", false, "fill missing age, gender and nationality from external services")
+	columns := flags.String("map", "", "comma-separated COLUMN=field mapping of file columns to person fields")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *batchSize < 1 {
+		return errors.New(importUsage)
+	}
+	path := flags.Arg(0)
+
+	mapping, err := parseColumnMapping(*columns)
+	if err != nil {
+		return err
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format == "jsonl" {
+		format = importer.FormatNDJSON
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := importer.NewReader(format, file, mapping)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openStorage(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	audit := model.AuditInfo{Actor: service.SystemActor, RequestID: "import " + filepath.Base(path)}
+	opts := service.ImportOptions{BatchSize: *batchSize, Enrich: *enrich}
+	peopleService := service.NewService(repo, logger)
+
+	report, err := peopleService.Import(context.Background(), reader, opts, audit, nil)
+	for _, rowErr := range report.Errors {
+		fmt.Fprintf(os.Stderr, "row %d: %s\n", rowErr.Row, rowErr.Error)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Imported %d people, rejected %d rows", report.Accepted, report.Rejected)
+	return nil
+}
+
+// parseColumnMapping разбирает сопоставление колонок вида "Имя=name,Фамилия=surname".
+func parseColumnMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	if value == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		column, field, ok := strings.Cut(pair, "=")
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid column mapping %q", pair)
+		}
+		mapping[column] = field
+	}
+	return mapping, nil
+}
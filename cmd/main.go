@@ -3,11 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"testProject/api"
 	"testProject/internal/config"
+	"testProject/internal/graphql"
+	"testProject/internal/grpc"
 	"testProject/internal/handlers"
 	"testProject/pkg/logging"
 	"testProject/repository"
@@ -28,11 +32,21 @@ func main() {
 	cfg := config.GetConfig()
 	logger.Info("Configuration initialized successfully.")
 
-	dbURL := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable",
-		cfg.DB.Host, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, cfg.DB.Port)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, logger, os.Args[2:]); err != nil {
+			logger.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(cfg, logger, os.Args[2:]); err != nil {
+			logger.Fatalf("Import failed: %v", err)
+		}
+		return
+	}
 
 	logger.Info("Creating repository...")
-	repo, err := repository.NewRepository(dbURL, logger)
+	repo, err := openStorage(cfg, logger)
 	if err != nil {
 		logger.Fatalf("Failed to create repository: %v", err)
 		return
@@ -44,17 +58,80 @@ func main() {
 	service := service.NewService(repo, logger)
 	logger.Info("Service created successfully.")
 
-	router := gin.Default()
-	handlers.RegisterRoutes(router, service)
+	// Фоновые задачи, в том числе фоновый импорт, прерываются при остановке сервера.
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	service.SetBackgroundContext(backgroundCtx)
+
+	// Паники обрабатывает Recovery из RegisterRoutes, чтобы ответ был в формате ошибок API.
+	router := gin.New()
+	router.Use(gin.Logger())
+	router.Use(handlers.AdminAuth(cfg.Admin.Token))
+	legacy := handlers.LegacyAPI{DeprecatedAt: cfg.API.LegacyDeprecatedAt, Sunset: cfg.API.LegacySunset}
+	var middleware []gin.HandlerFunc
+	if cfg.API.ValidateRequests {
+		spec, err := api.Load(context.Background())
+		if err != nil {
+			logger.Fatalf("Failed to load OpenAPI specification: %v", err)
+		}
+		middleware = append(middleware, handlers.OpenAPIValidation(spec, cfg.App.Env != config.EnvProduction))
+	}
+	// Остальные маршруты регистрируются после RegisterRoutes, чтобы получить общую цепочку middleware.
+	handlers.RegisterRoutes(router, service, repo, cfg.Idempotency.TTL, cfg.App.RequestTimeout, cfg.App.RouteTimeouts, cfg.Import.MaxUploadSize, legacy, middleware...)
+	handlers.RegisterHealthRoutes(router, repo)
+	handlers.RegisterDocsRoutes(router)
+	schema, err := graphql.NewSchema(service, logger)
+	if err != nil {
+		logger.Fatalf("Failed to parse GraphQL schema: %v", err)
+	}
+	handlers.RegisterGraphQLRoutes(router, schema, cfg.App.Env != config.EnvProduction)
+
+	go runPeriodically(backgroundCtx, cfg.Idempotency.CleanupInterval, func(ctx context.Context) {
+		deleted, err := repo.DeleteExpiredIdempotencyKeys(ctx)
+		if err != nil {
+			logger.Errorf("Failed to delete expired idempotency keys: %v", err)
+			return
+		}
+		logger.Debugf("Deleted %d expired idempotency keys", deleted)
+	})
+	if replicated, ok := repo.(*repository.Repository); ok && len(cfg.DB.Replicas) > 0 {
+		go runPeriodically(backgroundCtx, cfg.DB.ReplicaCheckInterval, replicated.CheckReplicas)
+	}
+	go runPeriodically(backgroundCtx, cfg.SoftDelete.PurgeInterval, func(ctx context.Context) {
+		purged, err := service.PurgeDeletedPeople(ctx, cfg.SoftDelete.Retention)
+		if err != nil {
+			logger.Errorf("Failed to purge deleted people: %v", err)
+			return
+		}
+		logger.Infof("Purged %d deleted people", purged)
+	})
+
+	// Контексты всех запросов наследуются от requestsCtx, его отмена прерывает запросы,
+	// которые не успели завершиться при остановке сервера.
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
 
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.App.Port),
-		Handler: router,
+		Addr:        fmt.Sprintf(":%d", cfg.App.Port),
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return requestsCtx },
 	}
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	grpcServer := grpc.NewServer(service, logger, cfg.Admin.Token, cfg.GRPC.RequestTimeout, cfg.GRPC.StreamTimeout)
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+	if err != nil {
+		logger.Fatalf("Failed to listen for gRPC: %v", err)
+	}
+	go func() {
+		logger.Info("Starting gRPC server at", grpcListener.Addr())
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.Fatal("Failed to start gRPC server:", err)
+		}
+	}()
+
 	go func() {
 		logger.Info("Starting server at", server.Addr, "on", time.Now())
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -66,14 +143,135 @@ func main() {
 
 	<-stop
 	logger.Info("Received termination signal. Shutting down gracefully...")
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// gRPC-сервер останавливается одновременно с HTTP-сервером в пределах того же времени.
+	grpcStopped := make(chan error, 1)
+	go func() { grpcStopped <- grpcServer.Shutdown(ctx) }()
+
 	if err := server.Shutdown(ctx); err != nil {
+		cancelRequests()
 		logger.Fatal("Server shutdown error:", err)
 	}
+	if err := <-grpcStopped; err != nil {
+		logger.Errorf("gRPC server shutdown error: %v", err)
+	}
 
 	logger.Info("Server gracefully stopped.")
 
+	// Соединения закрываются после остановки серверов и фоновых задач, которые ими пользуются.
+	if err := repo.Close(); err != nil {
+		logger.Errorf("Failed to close repository: %v", err)
+	}
+
+}
+
+// runPeriodically выполняет task раз в interval до отмены ctx.
+func runPeriodically(ctx context.Context, interval time.Duration, task func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			task(ctx)
+		}
+	}
+}
+
+// openStorage создает хранилище, выбранное в db.driver.
+// При db.auto_migrate к базе данных применяются непримененные миграции, файл SQLite мигрируется всегда.
+func openStorage(cfg *config.Config, logger *logging.Logger) (repository.Storage, error) {
+	if cfg.DB.Driver == "memory" {
+		logger.Warn("Using in-memory storage, data will be lost on shutdown")
+		opts, err := txOptions(cfg)
+		if err != nil {
+			return nil, err
+		}
+		repo := repository.NewMemoryRepository(logger)
+		repo.SetTxOptions(opts)
+		return repo, nil
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareRepository(cfg, logger, repo); err != nil {
+		repo.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+// prepareRepository применяет миграции и подключает реплики для чтения из конфигурации.
+func prepareRepository(cfg *config.Config, logger *logging.Logger, repo *repository.Repository) error {
+	if cfg.DB.AutoMigrate || cfg.DB.Driver == "sqlite" {
+		migrator, err := repository.NewMigrator(repo)
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to migrate the database: %v", err)
+		}
+		logger.Infof("Applied %d migrations", applied)
+	}
+	if len(cfg.DB.Replicas) > 0 {
+		if err := repo.AddReplicas(context.Background(), cfg.DB.Replicas, poolOptions(cfg)); err != nil {
+			return err
+		}
+		logger.Infof("Reading from %d replicas", len(cfg.DB.Replicas))
+	}
+	return nil
+}
+
+// poolOptions возвращает настройки пула соединений из конфигурации.
+func poolOptions(cfg *config.Config) repository.PoolOptions {
+	return repository.PoolOptions{
+		MaxOpenConns:    cfg.DB.MaxOpenConns,
+		MaxIdleConns:    cfg.DB.MaxIdleConns,
+		ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+	}
+}
+
+// openRepository подключается к базе данных Postgres или SQLite, выбранной в db.driver.
+func openRepository(cfg *config.Config, logger *logging.Logger) (*repository.Repository, error) {
+	opts, err := txOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var repo *repository.Repository
+	switch cfg.DB.Driver {
+	case "postgres":
+		dbURL := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable",
+			cfg.DB.Host, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, cfg.DB.Port)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.DB.ConnectTimeout)
+		defer cancel()
+		repo, err = repository.NewRepository(ctx, dbURL, poolOptions(cfg), logger)
+	case "sqlite":
+		repo, err = repository.NewSQLiteRepository(cfg.DB.Path, logger)
+	default:
+		return nil, fmt.Errorf("unknown db driver %q", cfg.DB.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	repo.SetTxOptions(opts)
+	return repo, nil
+}
+
+// txOptions возвращает настройки транзакций из db.isolation_level и db.tx_retries.
+func txOptions(cfg *config.Config) (repository.TxOptions, error) {
+	isolation, err := repository.ParseIsolationLevel(cfg.DB.IsolationLevel)
+	if err != nil {
+		return repository.TxOptions{}, err
+	}
+	return repository.TxOptions{Isolation: isolation, MaxRetries: cfg.DB.TxRetries}, nil
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"testProject/internal/config"
+	"testProject/pkg/logging"
+	"testProject/repository"
+)
+
+const migrateUsage = "usage: migrate up | down [N] | status | force VERSION"
+
+// runMigrate выполняет подкоманду migrate над базой данных из конфигурации:
+// up применяет все миграции, down N откатывает N последних (по умолчанию одну),
+// status выводит состояние миграций, force VERSION записывает версию схемы без выполнения SQL.
+func runMigrate(cfg *config.Config, logger *logging.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	migrator, err := repository.NewMigrator(repo)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Infof("Applied %d migrations", applied)
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, n)
+		if err != nil {
+			return err
+		}
+		logger.Infof("Reverted %d migrations", reverted)
+	case "status":
+		version, dirty, statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "version: %d, dirty: %v\n", version, dirty)
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(os.Stdout, "%06d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < -1 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			return err
+		}
+		logger.Infof("Forced schema version %d", version)
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -3,22 +3,74 @@ package config
 import (
 	"sync"
 	"testProject/pkg/logging"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+// EnvProduction окружение, в котором не включаются отладочные проверки, например проверка ответов по OpenAPI.
+const EnvProduction = "production"
+
 type Config struct {
 	DB struct {
+		Driver   string `yaml:"driver" env-default:"postgres"` // postgres, sqlite или memory (в памяти)
+		Path     string `yaml:"path" env-default:"people.db"`  // файл базы данных SQLite
 		Host     string `yaml:"host"`
 		User     string `yaml:"user"`
 		Password string `yaml:"password"`
 		Name     string `yaml:"name"`
 		Port     int    `yaml:"port"`
+
+		IsolationLevel string `yaml:"isolation_level"`            // уровень изоляции транзакций, например "read committed"
+		TxRetries      int    `yaml:"tx_retries" env-default:"3"` // попыток при ошибке сериализации
+		AutoMigrate    bool   `yaml:"auto_migrate"`               // применять миграции при запуске
+
+		MaxOpenConns    int           `yaml:"max_open_conns" env-default:"25"`
+		MaxIdleConns    int           `yaml:"max_idle_conns" env-default:"10"`
+		ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env-default:"30m"`
+		ConnectTimeout  time.Duration `yaml:"connect_timeout" env-default:"1m"` // сколько ждать базу данных при запуске
+
+		Replicas             []string      `yaml:"replicas"` // DSN реплик Postgres для чтения
+		ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" env-default:"10s"`
 	} `yaml:"db"`
 
 	App struct {
-		Port int `yaml:"port"`
+		Env            string                   `yaml:"env" env-default:"production"` // production или development
+		Port           int                      `yaml:"port"`
+		RequestTimeout time.Duration            `yaml:"request_timeout" env-default:"10s"`
+		RouteTimeouts  map[string]time.Duration `yaml:"route_timeouts"` // ключ "METHOD /path", например "POST /people"
 	} `yaml:"app"`
+
+	API struct {
+		LegacyDeprecatedAt time.Time `yaml:"legacy_deprecated_at"` // с какого момента пути без /api/v1 устарели
+		LegacySunset       time.Time `yaml:"legacy_sunset"`        // после какого момента пути без /api/v1 могут быть удалены
+
+		ValidateRequests bool `yaml:"validate_requests" env-default:"true"` // проверять запросы по спецификации OpenAPI, вне production проверяются и ответы
+	} `yaml:"api"`
+
+	Admin struct {
+		Token string `yaml:"token" env:"ADMIN_TOKEN"` // токен администратора для удаленных записей, пустой отключает доступ
+	} `yaml:"admin"`
+
+	GRPC struct {
+		Port           int           `yaml:"port" env-default:"9090"`
+		RequestTimeout time.Duration `yaml:"request_timeout" env-default:"10s"` // ограничение унарных вызовов
+		StreamTimeout  time.Duration `yaml:"stream_timeout" env-default:"1h"`   // ограничение потоков WatchPeople, клиент переподключается
+	} `yaml:"grpc"`
+
+	Import struct {
+		MaxUploadSize int64 `yaml:"max_upload_size" env-default:"104857600"` // наибольший размер загрузки импорта в байтах
+	} `yaml:"import"`
+
+	Idempotency struct {
+		TTL             time.Duration `yaml:"ttl" env-default:"24h"`
+		CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"1h"`
+	} `yaml:"idempotency"`
+
+	SoftDelete struct {
+		Retention     time.Duration `yaml:"retention" env-default:"720h"`
+		PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+	} `yaml:"soft_delete"`
 }
 
 var instance *Config
@@ -0,0 +1,259 @@
+// Package exporter записывает людей в файлы выгрузки.
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"testProject/internal/model"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+	"github.com/xuri/excelize/v2"
+)
+
+// Форматы файлов выгрузки.
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatXLSX    = "xlsx"
+	FormatParquet = "parquet"
+)
+
+// contentTypes типы содержимого файлов выгрузки по формату.
+var contentTypes = map[string]string{
+	FormatCSV:     "text/csv; charset=utf-8",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatXLSX:    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatParquet: "application/vnd.apache.parquet",
+}
+
+// Columns колонки файла выгрузки. Названия совпадают с колонками импорта, кроме служебных id, version
+// и времени записи created_at, updated_at и deleted_at.
+var Columns = []string{
+	"id", "name", "surname", "patronymic", "age", "gender", "nationality", "version", "created_at", "updated_at", "deleted_at",
+}
+
+// Writer записывает людей в файл выгрузки по одному. Close дописывает файл, но не закрывает нижележащий io.Writer.
+type Writer interface {
+	Write(person *model.Person) error
+	Close() error
+}
+
+// ContentType возвращает тип содержимого файла формата format.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// NewWriter возвращает Writer формата format, который пишет в w.
+// CSV, NDJSON и Parquet пишутся потоком, XLSX - архив, поэтому строки копятся во временном файле
+// и попадают в w при Close.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := &csvWriter{csv: csv.NewWriter(w)}
+		return writer, writer.csv.Write(Columns)
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	case FormatParquet:
+		return newParquetWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// record строка выгрузки.
+type record struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Surname     string     `json:"surname"`
+	Patronymic  string     `json:"patronymic"`
+	Age         int64      `json:"age"`
+	Gender      string     `json:"gender"`
+	Nationality string     `json:"nationality"`
+	Version     int64      `json:"version"`
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+}
+
+func newRecord(person *model.Person) record {
+	return record{
+		ID:          int64(person.ID),
+		Name:        person.Name,
+		Surname:     person.Surname,
+		Patronymic:  person.Patronymic,
+		Age:         int64(person.Age),
+		Gender:      person.Gender,
+		Nationality: person.Nationality,
+		Version:     int64(person.Version),
+		CreatedAt:   optionalTime(person.CreatedAt),
+		UpdatedAt:   optionalTime(person.UpdatedAt),
+		DeletedAt:   person.DeletedAt,
+	}
+}
+
+// optionalTime возвращает nil для нулевого времени, чтобы в выгрузке оно было пустым.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// formatTime возвращает время t в RFC 3339 UTC или пустую строку, если его нет.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// unixMilli возвращает время t в миллисекундах Unix или nil, если его нет.
+func unixMilli(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	millis := t.UnixMilli()
+	return &millis
+}
+
+// cells возвращает значения колонок Columns в текстовом виде.
+func (r record) cells() []string {
+	return []string{
+		strconv.FormatInt(r.ID, 10), r.Name, r.Surname, r.Patronymic, strconv.FormatInt(r.Age, 10),
+		r.Gender, r.Nationality, strconv.FormatInt(r.Version, 10),
+		formatTime(r.CreatedAt), formatTime(r.UpdatedAt), formatTime(r.DeletedAt),
+	}
+}
+
+type csvWriter struct {
+	csv *csv.Writer
+}
+
+func (w *csvWriter) Write(person *model.Person) error {
+	return w.csv.Write(newRecord(person).cells())
+}
+
+func (w *csvWriter) Close() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+type ndjsonWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(person *model.Person) error {
+	return w.encoder.Encode(newRecord(person))
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buffered.Flush()
+}
+
+// xlsxWriter записывает людей на первый лист книги XLSX.
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXWriter(out io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(file.GetSheetName(0))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w := &xlsxWriter{out: out, file: file, stream: stream}
+	header := make([]interface{}, len(Columns))
+	for i, column := range Columns {
+		header[i] = column
+	}
+	if err := w.writeRow(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *xlsxWriter) Write(person *model.Person) error {
+	r := newRecord(person)
+	return w.writeRow([]interface{}{
+		r.ID, r.Name, r.Surname, r.Patronymic, r.Age, r.Gender, r.Nationality, r.Version,
+		formatTime(r.CreatedAt), formatTime(r.UpdatedAt), formatTime(r.DeletedAt),
+	})
+}
+
+func (w *xlsxWriter) writeRow(values []interface{}) error {
+	w.row++
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	return w.stream.SetRow(cell, values)
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	return w.file.Write(w.out)
+}
+
+// parquetRow строка выгрузки в Parquet, время записи хранится в миллисекундах Unix.
+type parquetRow struct {
+	ID          int64  `parquet:"name=id, type=INT64"`
+	Name        string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Surname     string `parquet:"name=surname, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Patronymic  string `parquet:"name=patronymic, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Age         int64  `parquet:"name=age, type=INT64"`
+	Gender      string `parquet:"name=gender, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Nationality string `parquet:"name=nationality, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Version     int64  `parquet:"name=version, type=INT64"`
+	CreatedAt   *int64 `parquet:"name=created_at, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`
+	UpdatedAt   *int64 `parquet:"name=updated_at, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`
+	DeletedAt   *int64 `parquet:"name=deleted_at, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`
+}
+
+// parquetRowGroupSize размер группы строк Parquet: группа копится в памяти до записи в ответ.
+const parquetRowGroupSize = 8 << 20
+
+type parquetWriter struct {
+	parquet *writer.ParquetWriter
+}
+
+func newParquetWriter(w io.Writer) (*parquetWriter, error) {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(parquetRow), 1)
+	if err != nil {
+		return nil, err
+	}
+	pw.RowGroupSize = parquetRowGroupSize
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	return &parquetWriter{parquet: pw}, nil
+}
+
+func (w *parquetWriter) Write(person *model.Person) error {
+	r := newRecord(person)
+	row := parquetRow{
+		ID: r.ID, Name: r.Name, Surname: r.Surname, Patronymic: r.Patronymic, Age: r.Age,
+		Gender: r.Gender, Nationality: r.Nationality, Version: r.Version,
+		CreatedAt: unixMilli(r.CreatedAt), UpdatedAt: unixMilli(r.UpdatedAt), DeletedAt: unixMilli(r.DeletedAt),
+	}
+	return w.parquet.Write(row)
+}
+
+func (w *parquetWriter) Close() error {
+	return w.parquet.WriteStop()
+}
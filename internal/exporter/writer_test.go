@@ -0,0 +1,101 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	createdAt = time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	deletedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	people    = []model.Person{
+		{ID: 1, Name: "Ivan", Surname: "Ivanov", Age: 30, Gender: "male", Nationality: "RU", Version: 2, CreatedAt: createdAt, UpdatedAt: createdAt},
+		{ID: 2, Name: "Anna", Surname: "Petrova, \"Jr\"", Version: 1, DeletedAt: &deletedAt},
+	}
+)
+
+func export(t *testing.T, format string) []byte {
+	var out bytes.Buffer
+	writer, err := NewWriter(format, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	for i := range people {
+		if err := writer.Write(&people[i]); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	expected := "id,name,surname,patronymic,age,gender,nationality,version,created_at,updated_at,deleted_at\n" +
+		"1,Ivan,Ivanov,,30,male,RU,2,2024-04-01T09:00:00Z,2024-04-01T09:00:00Z,\n" +
+		"2,Anna,\"Petrova, \"\"Jr\"\"\",,0,,,1,,,2024-05-01T12:00:00Z\n"
+	if output := string(export(t, FormatCSV)); output != expected {
+		t.Errorf("Unexpected CSV:\n%s", output)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	lines := bytes.Split(bytes.TrimSpace(export(t, FormatNDJSON)), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal(lines[1], &row); err != nil {
+		t.Fatal(err)
+	}
+	if row["id"] != 2.0 || row["surname"] != "Petrova, \"Jr\"" || row["deleted_at"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("Unexpected row: %v", row)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	file, err := excelize.OpenReader(bytes.NewReader(export(t, FormatXLSX)))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer file.Close()
+
+	rows, err := file.GetRows(file.GetSheetName(0))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "id" || rows[1][1] != "Ivan" || rows[1][8] != "2024-04-01T09:00:00Z" || rows[2][10] != "2024-05-01T12:00:00Z" {
+		t.Errorf("Unexpected rows: %v", rows)
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	file, err := buffer.NewBufferFile(export(t, FormatParquet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := reader.NewParquetReader(file, new(parquetRow), 1)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer pr.ReadStop()
+
+	rows := make([]parquetRow, pr.GetNumRows())
+	if err := pr.Read(&rows); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(rows) != 2 || rows[0].Name != "Ivan" || rows[0].DeletedAt != nil ||
+		rows[0].CreatedAt == nil || *rows[0].CreatedAt != createdAt.UnixMilli() || rows[1].CreatedAt != nil ||
+		rows[1].DeletedAt == nil || *rows[1].DeletedAt != deletedAt.UnixMilli() {
+		t.Errorf("Unexpected rows: %+v", rows)
+	}
+}
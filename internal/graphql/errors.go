@@ -0,0 +1,76 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"testProject/internal/model"
+	"testProject/service"
+)
+
+// Коды ошибок в extensions.code ответа GraphQL.
+const (
+	CodeBadUserInput        = "BAD_USER_INPUT"
+	CodeNotFound            = "NOT_FOUND"
+	CodeForbidden           = "FORBIDDEN"
+	CodeConflict            = "CONFLICT"
+	CodeVersionMismatch     = "VERSION_MISMATCH"
+	CodeUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	CodeTimeout             = "TIMEOUT"
+	CodeInternal            = "INTERNAL"
+)
+
+// Error ошибка резолвера с кодом и нарушенными правилами полей в extensions ответа.
+type Error struct {
+	Message string
+	Code    string
+	Fields  model.ValidationErrors
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions возвращает поле extensions ошибки в ответе GraphQL.
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code}
+	if len(e.Fields) > 0 {
+		extensions["fields"] = e.Fields
+	}
+	return extensions
+}
+
+func invalidInput(message string) *Error {
+	return &Error{Message: message, Code: CodeBadUserInput}
+}
+
+// resolverError переводит ошибку сервиса в Error и логирует ее. Текст внутренних ошибок заменяется общим сообщением.
+func (r *Resolver) resolverError(err error) *Error {
+	resolverErr := &Error{Message: err.Error()}
+	switch {
+	case errors.Is(err, service.ErrVersionMismatch):
+		resolverErr.Message, resolverErr.Code = "person version does not match", CodeVersionMismatch
+	case errors.Is(err, service.ErrNotFound):
+		resolverErr.Code = CodeNotFound
+	case errors.Is(err, service.ErrForbidden):
+		resolverErr.Code = CodeForbidden
+	case errors.Is(err, service.ErrConflict):
+		resolverErr.Code = CodeConflict
+	case errors.Is(err, service.ErrValidation):
+		resolverErr.Code = CodeBadUserInput
+		errors.As(err, &resolverErr.Fields)
+	case errors.Is(err, service.ErrUpstreamUnavailable):
+		resolverErr.Message, resolverErr.Code = service.ErrUpstreamUnavailable.Error(), CodeUpstreamUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		resolverErr.Message, resolverErr.Code = "request timed out", CodeTimeout
+	default:
+		resolverErr.Message, resolverErr.Code = "internal error", CodeInternal
+	}
+
+	if resolverErr.Code == CodeInternal || resolverErr.Code == CodeUpstreamUnavailable || resolverErr.Code == CodeTimeout {
+		r.logger.Errorf("GraphQL resolver failed: %v", err)
+	} else {
+		r.logger.Warnf("GraphQL resolver failed: %v", err)
+	}
+	return resolverErr
+}
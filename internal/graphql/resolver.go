@@ -0,0 +1,313 @@
+// Package graphql реализует GraphQL API людей поверх service.Service.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/service"
+
+	gographql "github.com/graph-gophers/graphql-go"
+)
+
+// MaxPageSize наибольший размер страницы списка людей.
+const MaxPageSize = 100
+
+// maxDepth наибольшая вложенность запроса.
+const maxDepth = 10
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// NewSchema возвращает схему GraphQL, запросы и изменения которой выполняются через service.
+func NewSchema(service *service.Service, logger *logging.Logger) (*gographql.Schema, error) {
+	return gographql.ParseSchema(schemaSDL, &Resolver{service: service, logger: logger}, gographql.MaxDepth(maxDepth))
+}
+
+// auditKey ключ контекста со сведениями об авторе изменений.
+type auditKey struct{}
+
+// WithAudit возвращает контекст, в котором изменения записываются в историю от имени audit.
+func WithAudit(ctx context.Context, audit model.AuditInfo) context.Context {
+	return context.WithValue(ctx, auditKey{}, audit)
+}
+
+func auditFrom(ctx context.Context) model.AuditInfo {
+	audit, _ := ctx.Value(auditKey{}).(model.AuditInfo)
+	return audit
+}
+
+// Resolver корневой резолвер запросов и изменений.
+type Resolver struct {
+	service *service.Service
+	logger  *logging.Logger
+}
+
+// Person возвращает человека по идентификатору или nil, если его нет.
+func (r *Resolver) Person(ctx context.Context, args struct{ ID gographql.ID }) (*personResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	person, err := r.service.GetPersonById(ctx, id)
+	if errors.Is(err, service.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, r.resolverError(err)
+	}
+	return &personResolver{person: person}, nil
+}
+
+type personFilterInput struct {
+	Name           *string
+	Surname        *string
+	Patronymic     *string
+	Age            *int32
+	Gender         *string
+	Nationality    *string
+	IncludeDeleted *bool
+}
+
+type personSortInput struct {
+	Field     string
+	Direction string
+}
+
+// People возвращает страницу списка людей не больше MaxPageSize записей.
+// Курсор записи - service.EncodeCursor с ее смещением в списке.
+func (r *Resolver) People(ctx context.Context, args struct {
+	Filter *personFilterInput
+	Sort   *[]personSortInput
+	First  int32
+	After  *string
+}) (*connectionResolver, error) {
+	filter := model.PersonFilter{Fields: make(map[string]string), Limit: int(args.First)}
+	if filter.Limit < 0 || filter.Limit > MaxPageSize {
+		return nil, invalidInput(fmt.Sprintf("first must be between 0 and %d", MaxPageSize))
+	}
+	if args.After != nil {
+		offset, err := service.DecodeCursor(*args.After)
+		if err != nil {
+			return nil, invalidInput("invalid after cursor")
+		}
+		filter.Offset = offset + 1
+	}
+	if f := args.Filter; f != nil {
+		filter.Fields = model.PersonFieldValues{
+			Name: f.Name, Surname: f.Surname, Patronymic: f.Patronymic,
+			Age: f.Age, Gender: f.Gender, Nationality: f.Nationality,
+		}.Fields()
+		filter.IncludeDeleted = f.IncludeDeleted != nil && *f.IncludeDeleted
+	}
+	if args.Sort != nil {
+		for _, sort := range *args.Sort {
+			filter.Sort = append(filter.Sort, model.SortField{
+				Field: strings.ToLower(sort.Field),
+				Desc:  sort.Direction == "DESC",
+			})
+		}
+	}
+
+	page, err := r.service.GetPeoplePage(ctx, filter)
+	if err != nil {
+		return nil, r.resolverError(err)
+	}
+	connection := &connectionResolver{hasNextPage: page.HasNext}
+	for i := range page.People {
+		connection.edges = append(connection.edges, &edgeResolver{
+			cursor: service.EncodeCursor(filter.Offset + i),
+			node:   &personResolver{person: &page.People[i]},
+		})
+	}
+	return connection, nil
+}
+
+type createPersonInput struct {
+	Name       string
+	Surname    string
+	Patronymic *string
+}
+
+// CreatePerson создает человека с данными обогащения.
+func (r *Resolver) CreatePerson(ctx context.Context, args struct{ Input createPersonInput }) (*personResolver, error) {
+	person := &model.Person{Name: args.Input.Name, Surname: args.Input.Surname, Patronymic: stringValue(args.Input.Patronymic)}
+	if err := r.service.CreatePerson(ctx, person, auditFrom(ctx)); err != nil {
+		return nil, r.resolverError(err)
+	}
+	return &personResolver{person: person}, nil
+}
+
+type updatePersonInput struct {
+	Name        string
+	Surname     string
+	Patronymic  *string
+	Age         *int32
+	Gender      *string
+	Nationality *string
+}
+
+// UpdatePerson обновляет человека и возвращает его новое состояние.
+func (r *Resolver) UpdatePerson(ctx context.Context, args struct {
+	ID      gographql.ID
+	Input   updatePersonInput
+	Version *int32
+}) (*personResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	person := &model.Person{
+		ID:          uint(id),
+		Name:        args.Input.Name,
+		Surname:     args.Input.Surname,
+		Patronymic:  stringValue(args.Input.Patronymic),
+		Gender:      stringValue(args.Input.Gender),
+		Nationality: stringValue(args.Input.Nationality),
+	}
+	if args.Input.Age != nil {
+		person.Age = int(*args.Input.Age)
+	}
+	if args.Version != nil {
+		person.Version = int(*args.Version)
+	}
+	if err := r.service.UpdatePerson(ctx, person, auditFrom(ctx)); err != nil {
+		return nil, r.resolverError(err)
+	}
+	// Запись перечитывается с основной базы данных, чтобы вернуть поля, которых нет во входных данных,
+	// например createdAt и enrichment.
+	updated, err := r.service.GetPersonById(service.WithStrongConsistency(ctx), id)
+	if err != nil {
+		return nil, r.resolverError(err)
+	}
+	return &personResolver{person: updated}, nil
+}
+
+// DeletePerson удаляет человека и возвращает его идентификатор.
+func (r *Resolver) DeletePerson(ctx context.Context, args struct {
+	ID      gographql.ID
+	Version *int32
+}) (gographql.ID, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return "", err
+	}
+	var version int
+	if args.Version != nil {
+		version = int(*args.Version)
+	}
+	if err := r.service.DeletePerson(ctx, id, version, auditFrom(ctx)); err != nil {
+		return "", r.resolverError(err)
+	}
+	return args.ID, nil
+}
+
+type personResolver struct {
+	person *model.Person
+}
+
+func (r *personResolver) ID() gographql.ID {
+	return gographql.ID(strconv.FormatUint(uint64(r.person.ID), 10))
+}
+
+func (r *personResolver) Name() string        { return r.person.Name }
+func (r *personResolver) Surname() string     { return r.person.Surname }
+func (r *personResolver) Patronymic() string  { return r.person.Patronymic }
+func (r *personResolver) Age() int32          { return int32(r.person.Age) }
+func (r *personResolver) Gender() string      { return r.person.Gender }
+func (r *personResolver) Nationality() string { return r.person.Nationality }
+func (r *personResolver) Version() int32      { return int32(r.person.Version) }
+
+func (r *personResolver) CreatedAt() *gographql.Time {
+	if r.person.CreatedAt.IsZero() {
+		return nil
+	}
+	return &gographql.Time{Time: r.person.CreatedAt}
+}
+
+func (r *personResolver) UpdatedAt() *gographql.Time {
+	if r.person.UpdatedAt.IsZero() {
+		return nil
+	}
+	return &gographql.Time{Time: r.person.UpdatedAt}
+}
+
+func (r *personResolver) DeletedAt() *gographql.Time {
+	if r.person.DeletedAt == nil {
+		return nil
+	}
+	return &gographql.Time{Time: *r.person.DeletedAt}
+}
+
+func (r *personResolver) Enrichment() *enrichmentResolver {
+	if r.person.EnrichedAt == nil {
+		return nil
+	}
+	return &enrichmentResolver{person: r.person}
+}
+
+// enrichmentResolver данные человека, полученные обогащением.
+type enrichmentResolver struct {
+	person *model.Person
+}
+
+func (r *enrichmentResolver) EnrichedAt() gographql.Time {
+	return gographql.Time{Time: *r.person.EnrichedAt}
+}
+func (r *enrichmentResolver) Age() int32          { return int32(r.person.Age) }
+func (r *enrichmentResolver) Gender() string      { return r.person.Gender }
+func (r *enrichmentResolver) Nationality() string { return r.person.Nationality }
+
+type connectionResolver struct {
+	edges       []*edgeResolver
+	hasNextPage bool
+}
+
+func (r *connectionResolver) Edges() []*edgeResolver { return r.edges }
+
+func (r *connectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNextPage: r.hasNextPage}
+	if len(r.edges) > 0 {
+		cursor := r.edges[len(r.edges)-1].cursor
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+type edgeResolver struct {
+	cursor string
+	node   *personResolver
+}
+
+func (r *edgeResolver) Cursor() string        { return r.cursor }
+func (r *edgeResolver) Node() *personResolver { return r.node }
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool  { return r.hasNextPage }
+func (r *pageInfoResolver) EndCursor() *string { return r.endCursor }
+
+// parseID возвращает числовой идентификатор человека.
+func parseID(id gographql.ID) (int, error) {
+	value, err := strconv.Atoi(string(id))
+	if err != nil || value <= 0 {
+		return 0, invalidInput("invalid person ID")
+	}
+	return value, nil
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,148 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+)
+
+func TestSchema(t *testing.T) {
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	schema, err := NewSchema(service.NewService(repo, logger), logger)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	for _, name := range []string{"Ivan", "Petr", "Anna"} {
+		person := model.Person{Name: name, Surname: "Ivanov", Age: 30}
+		if err := repo.CreatePerson(context.Background(), &person, model.AuditInfo{}); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+	}
+	ctx := WithAudit(context.Background(), model.AuditInfo{Actor: "tester"})
+
+	exec := func(query string, variables map[string]interface{}, result interface{}) []map[string]interface{} {
+		t.Helper()
+		response := schema.Exec(ctx, query, "", variables)
+		var errs []map[string]interface{}
+		if len(response.Errors) > 0 {
+			data, _ := json.Marshal(response.Errors)
+			if err := json.Unmarshal(data, &errs); err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+		}
+		if result != nil && response.Data != nil {
+			if err := json.Unmarshal(response.Data, result); err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+		}
+		return errs
+	}
+
+	const peopleQuery = `query($after: String) {
+		people(sort: [{field: NAME}], first: 2, after: $after) {
+			edges { cursor node { id name } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+	var page struct {
+		People struct {
+			Edges []struct {
+				Node struct{ ID, Name string }
+			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   string
+			}
+		}
+	}
+	if errs := exec(peopleQuery, nil, &page); errs != nil {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+	if len(page.People.Edges) != 2 || page.People.Edges[0].Node.Name != "Anna" || !page.People.PageInfo.HasNextPage {
+		t.Fatalf("Unexpected first page %+v", page)
+	}
+	if errs := exec(peopleQuery, map[string]interface{}{"after": page.People.PageInfo.EndCursor}, &page); errs != nil {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+	if len(page.People.Edges) != 1 || page.People.Edges[0].Node.Name != "Petr" || page.People.PageInfo.HasNextPage {
+		t.Errorf("Unexpected second page %+v", page)
+	}
+
+	var found struct {
+		Person *struct {
+			Name       string
+			Version    int
+			Enrichment *struct{ Age int }
+		}
+	}
+	if errs := exec(`{ person(id: "1") { name version enrichment { age } } }`, nil, &found); errs != nil {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+	if found.Person == nil || found.Person.Name != "Ivan" || found.Person.Version != 1 || found.Person.Enrichment != nil {
+		t.Errorf("Unexpected person %+v", found.Person)
+	}
+	if errs := exec(`{ person(id: "42") { name } }`, nil, &found); errs != nil || found.Person != nil {
+		t.Errorf("Expected null person, but got %+v %v", found.Person, errs)
+	}
+
+	enrichedAt := time.Now()
+	enriched := model.Person{Name: "Olga", Surname: "Olgina", Age: 35, EnrichedAt: &enrichedAt}
+	if err := repo.CreatePerson(context.Background(), &enriched, model.AuditInfo{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	var updated struct {
+		UpdatePerson struct {
+			Name       string
+			Version    int
+			CreatedAt  *string
+			Enrichment *struct{ Age int }
+		}
+	}
+	const update = `mutation($id: ID!, $version: Int) {
+		updatePerson(id: $id, input: {name: "Oleg", surname: "Olegov", age: 40}, version: $version) {
+			name version createdAt enrichment { age }
+		}
+	}`
+	if errs := exec(update, map[string]interface{}{"id": "1", "version": 1}, &updated); errs != nil {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+	if updated.UpdatePerson.Name != "Oleg" || updated.UpdatePerson.Version != 2 || updated.UpdatePerson.CreatedAt == nil {
+		t.Errorf("Unexpected updated person %+v", updated)
+	}
+	// Обновленная запись возвращается целиком, а не только из входных данных.
+	if errs := exec(update, map[string]interface{}{"id": fmt.Sprint(enriched.ID)}, &updated); errs != nil {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+	if updated.UpdatePerson.CreatedAt == nil || updated.UpdatePerson.Enrichment == nil || updated.UpdatePerson.Enrichment.Age != 40 {
+		t.Errorf("Unexpected updated person %+v", updated)
+	}
+	errs := exec(update, map[string]interface{}{"id": "1", "version": 1}, nil)
+	if len(errs) != 1 || errs[0]["extensions"].(map[string]interface{})["code"] != CodeVersionMismatch {
+		t.Errorf("Expected version mismatch, but got %v", errs)
+	}
+
+	errs = exec(`mutation { createPerson(input: {name: "", surname: "Ivanov"}) { id } }`, nil, nil)
+	if len(errs) != 1 || errs[0]["extensions"].(map[string]interface{})["code"] != CodeBadUserInput {
+		t.Errorf("Expected validation error, but got %v", errs)
+	}
+
+	var deleted struct{ DeletePerson string }
+	if errs := exec(`mutation { deletePerson(id: "2") }`, nil, &deleted); errs != nil || deleted.DeletePerson != "2" {
+		t.Fatalf("Unexpected delete result %+v %v", deleted, errs)
+	}
+	changes, err := repo.GetPersonHistory(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if last := changes[len(changes)-1]; last.Actor != "tester" {
+		t.Errorf("Unexpected actor %q", last.Actor)
+	}
+}
@@ -0,0 +1,68 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/service"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorCode возвращает код gRPC для ошибки сервиса.
+// Несовпадение версии и конфликт параллельных изменений - Aborted, ошибки без типа - Internal.
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, service.ErrVersionMismatch), errors.Is(err, service.ErrConflict):
+		return codes.Aborted
+	case errors.Is(err, service.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, service.ErrForbidden):
+		return codes.PermissionDenied
+	case errors.Is(err, service.ErrValidation):
+		return codes.InvalidArgument
+	case errors.Is(err, service.ErrUpstreamUnavailable):
+		return codes.Unavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+	return codes.Internal
+}
+
+// statusError переводит ошибку сервиса в статус gRPC и логирует ее. Текст внутренних ошибок заменяется message,
+// нарушенные правила полей передаются в деталях BadRequest.
+func statusError(logger *logging.Logger, method string, err error, message string) error {
+	code := errorCode(err)
+	switch code {
+	case codes.Aborted, codes.NotFound, codes.PermissionDenied, codes.InvalidArgument:
+		logger.Warnf("gRPC %s failed with %s: %v", method, code, err)
+		message = err.Error()
+	case codes.Unavailable:
+		logger.Errorf("gRPC %s failed with %s: %v", method, code, err)
+		message = service.ErrUpstreamUnavailable.Error()
+	default:
+		logger.Errorf("gRPC %s failed with %s: %v", method, code, err)
+	}
+
+	st := status.New(code, message)
+	var fieldErrs model.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		badRequest := &errdetails.BadRequest{}
+		for _, fieldErr := range fieldErrs {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fieldErr.Field,
+				Description: fieldErr.Message,
+			})
+		}
+		if detailed, err := st.WithDetails(badRequest); err == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
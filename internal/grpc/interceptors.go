@@ -0,0 +1,98 @@
+package grpc
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"testProject/pkg/logging"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// loggingInterceptor логирует каждый унарный вызов с кодом ответа и длительностью, как журнал запросов HTTP.
+func loggingInterceptor(logger *logging.Logger) gogrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logger.Infof("gRPC %s %s %v", info.FullMethod, status.Code(err), time.Since(start))
+		return resp, err
+	}
+}
+
+// streamLoggingInterceptor логирует каждый потоковый вызов после его завершения.
+func streamLoggingInterceptor(logger *logging.Logger) gogrpc.StreamServerInterceptor {
+	return func(srv interface{}, stream gogrpc.ServerStream, info *gogrpc.StreamServerInfo, handler gogrpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		logger.Infof("gRPC %s %s %v", info.FullMethod, status.Code(err), time.Since(start))
+		return err
+	}
+}
+
+// recoveryInterceptor переводит панику в унарном вызове в ошибку Internal, чтобы она не остановила сервер.
+func recoveryInterceptor(logger *logging.Logger) gogrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredError(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// streamRecoveryInterceptor переводит панику в потоковом вызове в ошибку Internal.
+func streamRecoveryInterceptor(logger *logging.Logger) gogrpc.StreamServerInterceptor {
+	return func(srv interface{}, stream gogrpc.ServerStream, info *gogrpc.StreamServerInfo, handler gogrpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredError(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+// recoveredError логирует панику в вызове method со стеком и возвращает ошибку Internal без подробностей.
+func recoveredError(logger *logging.Logger, method string, recovered interface{}) error {
+	logger.Errorf("gRPC %s panicked: %v\n%s", method, recovered, debug.Stack())
+	return status.Error(codes.Internal, "internal error")
+}
+
+// timeoutInterceptor ограничивает унарный вызов временем timeout. Более ранний срок клиента сохраняется,
+// нулевой timeout отключает ограничение.
+func timeoutInterceptor(timeout time.Duration) gogrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (interface{}, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// streamTimeoutInterceptor ограничивает потоковый вызов временем timeout, как timeoutInterceptor.
+func streamTimeoutInterceptor(timeout time.Duration) gogrpc.StreamServerInterceptor {
+	return func(srv interface{}, stream gogrpc.ServerStream, info *gogrpc.StreamServerInfo, handler gogrpc.StreamHandler) error {
+		if timeout <= 0 {
+			return handler(srv, stream)
+		}
+		ctx, cancel := context.WithTimeout(stream.Context(), timeout)
+		defer cancel()
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// contextStream поток вызова с замененным контекстом.
+type contextStream struct {
+	gogrpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
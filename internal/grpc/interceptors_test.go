@@ -0,0 +1,68 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"testProject/pkg/logging"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInterceptors(t *testing.T) {
+	logger := logging.GetLogger()
+	info := &gogrpc.UnaryServerInfo{FullMethod: "/people.v1.PeopleService/GetPerson"}
+
+	t.Run("Recovery", func(t *testing.T) {
+		_, err := recoveryInterceptor(logger)(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+		if status.Code(err) != codes.Internal || status.Convert(err).Message() != "internal error" {
+			t.Errorf("Expected Internal without details, got %v", err)
+		}
+
+		streamInfo := &gogrpc.StreamServerInfo{FullMethod: "/people.v1.PeopleService/WatchPeople"}
+		err = streamRecoveryInterceptor(logger)(nil, &contextStream{ctx: context.Background()}, streamInfo, func(srv interface{}, stream gogrpc.ServerStream) error {
+			panic("boom")
+		})
+		if status.Code(err) != codes.Internal {
+			t.Errorf("Expected Internal, got %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := timeoutInterceptor(time.Minute)(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			if deadline, ok := ctx.Deadline(); !ok || deadline.Before(start.Add(time.Minute)) {
+				t.Errorf("Expected deadline in a minute, got %v", deadline)
+			}
+			return nil, nil
+		})
+		if err != nil {
+			t.Errorf("Expected no error, but got %v", err)
+		}
+
+		// Более ранний срок клиента не продлевается.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		clientDeadline, _ := ctx.Deadline()
+		_, _ = timeoutInterceptor(time.Minute)(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			if deadline, _ := ctx.Deadline(); !deadline.Equal(clientDeadline) {
+				t.Errorf("Expected client deadline %v, got %v", clientDeadline, deadline)
+			}
+			return nil, nil
+		})
+
+		streamInfo := &gogrpc.StreamServerInfo{FullMethod: "/people.v1.PeopleService/WatchPeople"}
+		err = streamTimeoutInterceptor(time.Millisecond)(nil, &contextStream{ctx: context.Background()}, streamInfo, func(srv interface{}, stream gogrpc.ServerStream) error {
+			<-stream.Context().Done()
+			return status.FromContextError(stream.Context().Err()).Err()
+		})
+		if status.Code(err) != codes.DeadlineExceeded {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,265 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	peoplev1 "testProject/api/people/v1"
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// ActorMetadata ключ метаданных с именем автора изменений для истории изменений.
+	// Как и заголовок X-Actor, значение не проверяется и может быть подделано клиентом.
+	ActorMetadata = "x-actor"
+	// RequestIDMetadata ключ метаданных с идентификатором запроса.
+	RequestIDMetadata = "x-request-id"
+	// AuthorizationMetadata ключ метаданных с токеном администратора вида "Bearer <токен>".
+	AuthorizationMetadata = "authorization"
+
+	anonymousActor      = "anonymous"
+	maxMetadataIDLength = 255
+
+	defaultPageSize = 10
+	// MaxPageSize наибольший размер страницы ListPeople.
+	MaxPageSize = 100
+)
+
+// peopleServer реализует peoplev1.PeopleServiceServer.
+type peopleServer struct {
+	peoplev1.UnimplementedPeopleServiceServer
+
+	service *service.Service
+	logger  *logging.Logger
+	done    <-chan struct{}
+}
+
+func (s *peopleServer) CreatePerson(ctx context.Context, request *peoplev1.CreatePersonRequest) (*peoplev1.Person, error) {
+	person := &model.Person{Name: request.Name, Surname: request.Surname, Patronymic: request.Patronymic}
+	if err := s.service.CreatePerson(ctx, person, auditInfo(ctx)); err != nil {
+		return nil, statusError(s.logger, "CreatePerson", err, "failed to create person")
+	}
+	return newPerson(person), nil
+}
+
+func (s *peopleServer) GetPerson(ctx context.Context, request *peoplev1.GetPersonRequest) (*peoplev1.Person, error) {
+	id, err := personID(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	person, err := s.service.GetPersonById(ctx, id)
+	if err != nil {
+		return nil, statusError(s.logger, "GetPerson", err, "failed to get person by ID")
+	}
+	return newPerson(person), nil
+}
+
+// ListPeople возвращает страницу списка людей. Токен страницы - курсор service.EncodeCursor
+// со смещением ее первой записи.
+func (s *peopleServer) ListPeople(ctx context.Context, request *peoplev1.ListPeopleRequest) (*peoplev1.ListPeopleResponse, error) {
+	filter := model.PersonFilter{
+		Fields: model.PersonFieldValues{
+			Name: request.Name, Surname: request.Surname, Patronymic: request.Patronymic,
+			Age: request.Age, Gender: request.Gender, Nationality: request.Nationality,
+		}.Fields(),
+		IncludeDeleted: request.IncludeDeleted,
+		Limit:          int(request.PageSize),
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit < 0 || filter.Limit > MaxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 0 and %d", MaxPageSize)
+	}
+	if request.PageToken != "" {
+		offset, err := service.DecodeCursor(request.PageToken)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		filter.Offset = offset
+	}
+	sort, err := parseOrderBy(request.OrderBy)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	filter.Sort = sort
+
+	page, err := s.service.GetPeoplePage(ctx, filter)
+	if err != nil {
+		return nil, statusError(s.logger, "ListPeople", err, "failed to get people")
+	}
+	response := &peoplev1.ListPeopleResponse{}
+	if page.HasNext {
+		response.NextPageToken = service.EncodeCursor(filter.Offset + filter.Limit)
+	}
+	for i := range page.People {
+		response.People = append(response.People, newPerson(&page.People[i]))
+	}
+	return response, nil
+}
+
+func (s *peopleServer) UpdatePerson(ctx context.Context, request *peoplev1.UpdatePersonRequest) (*peoplev1.Person, error) {
+	id, err := personID(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	person := &model.Person{
+		ID:          uint(id),
+		Version:     int(request.Version),
+		Name:        request.Name,
+		Surname:     request.Surname,
+		Patronymic:  request.Patronymic,
+		Age:         int(request.Age),
+		Gender:      request.Gender,
+		Nationality: request.Nationality,
+	}
+	if err := s.service.UpdatePerson(ctx, person, auditInfo(ctx)); err != nil {
+		return nil, statusError(s.logger, "UpdatePerson", err, "failed to update person")
+	}
+	return newPerson(person), nil
+}
+
+func (s *peopleServer) DeletePerson(ctx context.Context, request *peoplev1.DeletePersonRequest) (*peoplev1.DeletePersonResponse, error) {
+	id, err := personID(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.service.DeletePerson(ctx, id, int(request.Version), auditInfo(ctx)); err != nil {
+		return nil, statusError(s.logger, "DeletePerson", err, "failed to delete person")
+	}
+	return &peoplev1.DeletePersonResponse{}, nil
+}
+
+// WatchPeople отправляет изменения людей до отмены вызова или остановки сервера.
+// Заголовки ответа отправляются после подписки на изменения. Если клиент не успевает читать изменения,
+// поток завершается с ResourceExhausted.
+func (s *peopleServer) WatchPeople(_ *peoplev1.WatchPeopleRequest, stream peoplev1.PeopleService_WatchPeopleServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	events := s.service.Subscribe(ctx)
+	// Заголовки отправляются сразу, чтобы клиент знал, что изменения после этого момента не пропадут.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case event, ok := <-events:
+			if !ok {
+				if err := ctx.Err(); err != nil {
+					return status.FromContextError(err).Err()
+				}
+				return status.Error(codes.ResourceExhausted, "client is too slow to receive person events")
+			}
+			if err := stream.Send(newPersonEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// operations операции событий по операциям истории изменений.
+var operations = map[string]peoplev1.PersonEvent_Operation{
+	model.OperationCreate:  peoplev1.PersonEvent_OPERATION_CREATE,
+	model.OperationUpdate:  peoplev1.PersonEvent_OPERATION_UPDATE,
+	model.OperationDelete:  peoplev1.PersonEvent_OPERATION_DELETE,
+	model.OperationRestore: peoplev1.PersonEvent_OPERATION_RESTORE,
+}
+
+func newPersonEvent(event service.PersonEvent) *peoplev1.PersonEvent {
+	return &peoplev1.PersonEvent{Operation: operations[event.Operation], Person: newPerson(&event.Person)}
+}
+
+func newPerson(person *model.Person) *peoplev1.Person {
+	response := &peoplev1.Person{
+		Id:          uint64(person.ID),
+		Name:        person.Name,
+		Surname:     person.Surname,
+		Patronymic:  person.Patronymic,
+		Age:         int32(person.Age),
+		Gender:      person.Gender,
+		Nationality: person.Nationality,
+		Version:     int64(person.Version),
+	}
+	if !person.CreatedAt.IsZero() {
+		response.CreatedAt = timestamppb.New(person.CreatedAt)
+	}
+	if !person.UpdatedAt.IsZero() {
+		response.UpdatedAt = timestamppb.New(person.UpdatedAt)
+	}
+	if person.DeletedAt != nil {
+		response.DeletedAt = timestamppb.New(*person.DeletedAt)
+	}
+	if person.EnrichedAt != nil {
+		response.EnrichedAt = timestamppb.New(*person.EnrichedAt)
+	}
+	return response
+}
+
+// personID проверяет идентификатор человека из запроса.
+func personID(id uint64) (int, error) {
+	if id == 0 || id > uint64(^uint(0)>>1) {
+		return 0, status.Error(codes.InvalidArgument, "invalid person ID")
+	}
+	return int(id), nil
+}
+
+// parseOrderBy разбирает поле order_by вида "-age,name" в поля сортировки.
+func parseOrderBy(value string) ([]model.SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []model.SortField
+	for _, part := range strings.Split(value, ",") {
+		field := model.SortField{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(field.Field, "-") {
+			field.Field, field.Desc = field.Field[1:], true
+		}
+		if !isSortField(field.Field) {
+			return nil, fmt.Errorf("unknown order_by field %q", field.Field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func isSortField(field string) bool {
+	for _, sortField := range model.PersonSortFields {
+		if sortField == field {
+			return true
+		}
+	}
+	return false
+}
+
+// auditInfo возвращает автора и идентификатор запроса для истории изменений из метаданных вызова.
+// Если идентификатора запроса нет, генерируется новый.
+func auditInfo(ctx context.Context) model.AuditInfo {
+	audit := model.AuditInfo{Actor: anonymousActor}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(ActorMetadata); len(values) > 0 && values[0] != "" && len(values[0]) <= maxMetadataIDLength {
+		audit.Actor = values[0]
+	}
+	if values := md.Get(RequestIDMetadata); len(values) > 0 && values[0] != "" && len(values[0]) <= maxMetadataIDLength {
+		audit.RequestID = values[0]
+	} else {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err == nil {
+			audit.RequestID = hex.EncodeToString(id)
+		}
+	}
+	return audit
+}
@@ -0,0 +1,157 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	peoplev1 "testProject/api/people/v1"
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestPeopleService(t *testing.T) {
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	for _, name := range []string{"Ivan", "Petr", "Anna"} {
+		person := model.Person{Name: name, Surname: "Ivanov", Age: 30}
+		if err := repo.CreatePerson(context.Background(), &person, model.AuditInfo{}); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+	}
+
+	server := NewServer(service.NewService(repo, logger), logger, "secret", time.Minute, time.Minute)
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	conn, err := gogrpc.Dial("bufnet",
+		gogrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer conn.Close()
+	client := peoplev1.NewPeopleServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ActorMetadata, "tester")
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "people.v1.PeopleService"})
+	if err != nil || health.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Unexpected health %v %v", health, err)
+	}
+
+	page, err := client.ListPeople(ctx, &peoplev1.ListPeopleRequest{OrderBy: "name", PageSize: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(page.People) != 2 || page.People[0].Name != "Anna" || page.NextPageToken == "" {
+		t.Fatalf("Unexpected first page %v", page)
+	}
+	page, err = client.ListPeople(ctx, &peoplev1.ListPeopleRequest{OrderBy: "name", PageSize: 2, PageToken: page.NextPageToken})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(page.People) != 1 || page.People[0].Name != "Petr" || page.NextPageToken != "" {
+		t.Errorf("Unexpected second page %v", page)
+	}
+
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	defer cancelWatch()
+	watch, err := client.WatchPeople(watchCtx, &peoplev1.WatchPeopleRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	// Заголовки потока приходят после подписки на изменения.
+	if _, err := watch.Header(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	updated, err := client.UpdatePerson(ctx, &peoplev1.UpdatePersonRequest{Id: 1, Version: 1, Name: "Oleg", Surname: "Olegov", Age: 40})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if updated.Name != "Oleg" || updated.Version != 2 || updated.CreatedAt == nil {
+		t.Errorf("Unexpected updated person %v", updated)
+	}
+	event, err := watch.Recv()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if event.Operation != peoplev1.PersonEvent_OPERATION_UPDATE || event.Person.Name != "Oleg" {
+		t.Errorf("Unexpected event %v", event)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"version mismatch", func() error {
+			_, err := client.DeletePerson(ctx, &peoplev1.DeletePersonRequest{Id: 1, Version: 1})
+			return err
+		}, codes.Aborted},
+		{"not found", func() error {
+			_, err := client.GetPerson(ctx, &peoplev1.GetPersonRequest{Id: 42})
+			return err
+		}, codes.NotFound},
+		{"invalid id", func() error {
+			_, err := client.GetPerson(ctx, &peoplev1.GetPersonRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid person", func() error {
+			_, err := client.CreatePerson(ctx, &peoplev1.CreatePersonRequest{Surname: "Ivanov"})
+			return err
+		}, codes.InvalidArgument},
+		{"deleted without admin", func() error {
+			_, err := client.ListPeople(ctx, &peoplev1.ListPeopleRequest{IncludeDeleted: true})
+			return err
+		}, codes.PermissionDenied},
+		{"deleted with admin", func() error {
+			_, err := client.ListPeople(metadata.AppendToOutgoingContext(ctx, AuthorizationMetadata, "Bearer secret"), &peoplev1.ListPeopleRequest{IncludeDeleted: true})
+			return err
+		}, codes.OK},
+		{"invalid order", func() error {
+			_, err := client.ListPeople(ctx, &peoplev1.ListPeopleRequest{OrderBy: "version"})
+			return err
+		}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != tt.want {
+				t.Errorf("Expected %s, but got %s", tt.want, code)
+			}
+		})
+	}
+
+	if _, err := client.DeletePerson(ctx, &peoplev1.DeletePersonRequest{Id: 2}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if event, err := watch.Recv(); err != nil || event.Operation != peoplev1.PersonEvent_OPERATION_DELETE || event.Person.Id != 2 {
+		t.Errorf("Unexpected event %v %v", event, err)
+	}
+	changes, err := repo.GetPersonHistory(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if last := changes[len(changes)-1]; last.Actor != "tester" || last.RequestID == "" {
+		t.Errorf("Unexpected audit %q %q", last.Actor, last.RequestID)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if _, err := watch.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected watch to end with Unavailable, but got %v", err)
+	}
+}
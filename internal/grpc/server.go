@@ -0,0 +1,95 @@
+// Package grpc реализует gRPC API людей поверх service.Service.
+package grpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	peoplev1 "testProject/api/people/v1"
+	"testProject/pkg/logging"
+	"testProject/service"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server gRPC-сервер с PeopleService, сервисами здоровья и рефлексии.
+type Server struct {
+	server *gogrpc.Server
+	health *health.Server
+	logger *logging.Logger
+
+	// Закрывается при остановке сервера, чтобы завершить потоки WatchPeople.
+	done chan struct{}
+}
+
+// NewServer создает gRPC-сервер, вызовы PeopleService которого выполняются через service.
+// Вызовы с метаданными "authorization: Bearer <adminToken>" выполняются от имени администратора,
+// пустой adminToken отключает доступ администратора. Унарные вызовы ограничены временем requestTimeout,
+// потоковые - streamTimeout. Все вызовы логируются, паника в вызове завершает его с Internal.
+func NewServer(service *service.Service, logger *logging.Logger, adminToken string, requestTimeout, streamTimeout time.Duration) *Server {
+	s := &Server{
+		server: gogrpc.NewServer(
+			gogrpc.ChainUnaryInterceptor(
+				loggingInterceptor(logger),
+				recoveryInterceptor(logger),
+				timeoutInterceptor(requestTimeout),
+				adminInterceptor(adminToken),
+			),
+			gogrpc.ChainStreamInterceptor(
+				streamLoggingInterceptor(logger),
+				streamRecoveryInterceptor(logger),
+				streamTimeoutInterceptor(streamTimeout),
+			),
+		),
+		health: health.NewServer(),
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+	peoplev1.RegisterPeopleServiceServer(s.server, &peopleServer{service: service, logger: logger, done: s.done})
+	healthpb.RegisterHealthServer(s.server, s.health)
+	reflection.Register(s.server)
+	s.health.SetServingStatus(peoplev1.PeopleService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return s
+}
+
+// Serve принимает соединения на listener до остановки сервера.
+func (s *Server) Serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+// Shutdown переводит сервисы в NOT_SERVING, завершает потоки WatchPeople и ждет завершения остальных вызовов.
+// Если ctx отменяется раньше, незавершенные вызовы прерываются.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+	close(s.done)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// adminInterceptor отмечает унарные вызовы с токеном администратора в метаданных authorization.
+func adminInterceptor(token string) gogrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var authorization string
+		if values := md.Get(AuthorizationMetadata); len(values) > 0 {
+			authorization = values[0]
+		}
+		return handler(service.AuthorizeAdmin(ctx, authorization, token), req)
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth middleware: запрос с заголовком "Authorization: Bearer <token>" выполняется от имени администратора,
+// которому доступны удаленные записи и их восстановление. Остальным запросам эти операции возвращают 403.
+// Пустой token отключает доступ администратора.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(service.AuthorizeAdmin(c.Request.Context(), c.GetHeader("Authorization"), token))
+		c.Next()
+	}
+}
+
+// RequireAdmin middleware отвечает 403 на запросы не от администратора, см. AdminAuth.
+// Используется для маршрутов, которые целиком доступны только администратору.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.IsAdmin(c.Request.Context()) {
+			respondProblem(c, http.StatusForbidden, service.ErrForbidden.Error())
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"embed"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// assets сторонние скрипты и стили страниц документации и GraphQL, источники перечислены в assets/NOTICE.
+//
+//go:embed assets/*.js assets/*.css
+var assets embed.FS
+
+// assetCacheControl кеширование встроенных файлов: они меняются только вместе с версией приложения.
+const assetCacheControl = "public, max-age=86400"
+
+// assetHandler отдает встроенный файл assets/name с типом содержимого по его расширению.
+func assetHandler(name string) gin.HandlerFunc {
+	data, err := assets.ReadFile(path.Join("assets", name))
+	if err != nil {
+		panic(err)
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", assetCacheControl)
+		c.Data(http.StatusOK, contentType, data)
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConsistencyParam параметр запроса, которым клиент выбирает согласованность чтения.
+const ConsistencyParam = "consistency"
+
+// Consistency middleware: с ?consistency=strong запрос читает данные с основной базы данных,
+// по умолчанию (eventual) чтения могут выполняться на репликах, отстающих от нее.
+func Consistency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Query(ConsistencyParam) {
+		case "", "eventual":
+		case "strong":
+			c.Request = c.Request.WithContext(service.WithStrongConsistency(c.Request.Context()))
+		default:
+			respondProblem(c, http.StatusBadRequest, "invalid consistency parameter")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LegacyAPI сроки поддержки маршрутов без префикса версии.
+// DeprecatedAt - с какого момента маршруты устарели, Sunset - после какого момента они могут перестать работать.
+// Нулевое значение означает, что срок не объявлен.
+type LegacyAPI struct {
+	DeprecatedAt time.Time
+	Sunset       time.Time
+}
+
+// Deprecation middleware помечает ответы устаревших маршрутов заголовками Deprecation (RFC 9745) и Sunset (RFC 8594)
+// и указывает в заголовке Link путь с префиксом successor, которым их следует заменить.
+func Deprecation(legacy LegacyAPI, successor string) gin.HandlerFunc {
+	deprecation := "true"
+	if !legacy.DeprecatedAt.IsZero() {
+		deprecation = "@" + strconv.FormatInt(legacy.DeprecatedAt.Unix(), 10)
+	}
+	var sunset string
+	if !legacy.Sunset.IsZero() {
+		sunset = legacy.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+		c.Header("Link", "<"+successor+c.Request.URL.Path+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
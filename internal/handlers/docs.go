@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"testProject/api"
+	"testProject/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// docsPage страница документации Redoc по спецификации /openapi.json.
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>People API</title>
+</head>
+<body>
+  <redoc spec-url="/openapi.json"></redoc>
+  <script src="/docs/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+// RegisterDocsRoutes регистрирует /openapi.json со спецификацией OpenAPI и /docs со страницей документации по ней.
+// Скрипт Redoc встроен в приложение, поэтому документация открывается и без доступа к интернету.
+func RegisterDocsRoutes(router *gin.Engine) {
+	logger := logging.GetLogger()
+
+	router.GET("/openapi.json", func(c *gin.Context) {
+		spec, err := api.OpenAPIJSON()
+		if err != nil {
+			logger.Errorf("Failed to convert OpenAPI specification: %v", err)
+			respondProblem(c, http.StatusInternalServerError, "failed to load OpenAPI specification")
+			return
+		}
+		c.Data(http.StatusOK, "application/json", spec)
+	})
+
+	router.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+	})
+	router.GET("/docs/redoc.standalone.js", assetHandler("redoc.standalone.js"))
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPIRoutes сверяет пути спецификации OpenAPI с маршрутами, которые регистрирует RegisterRoutes.
+func TestOpenAPIRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+	RegisterDocsRoutes(router)
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		if path, ok := strings.CutPrefix(route.Path, APIPrefixV1); ok {
+			registered[route.Method+" "+path] = true
+		} else if route.Path != "/openapi.json" && !strings.HasPrefix(route.Path, "/docs") && !registered[route.Method+" "+route.Path] {
+			// Устаревшие пути без префикса должны повторять маршруты версии 1.
+			registered[route.Method+" "+route.Path] = false
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected /openapi.json response %d", w.Code)
+	}
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Expected JSON specification, got %v", err)
+	}
+	if spec.OpenAPI != "3.1.0" {
+		t.Errorf("Expected OpenAPI 3.1.0, got %q", spec.OpenAPI)
+	}
+
+	// Страница документации загружает Redoc с сервера, а не с CDN.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `src="/docs/redoc.standalone.js"`) || strings.Contains(w.Body.String(), "https://") {
+		t.Errorf("Unexpected /docs response %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/redoc.standalone.js", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") || w.Body.Len() == 0 {
+		t.Errorf("Unexpected Redoc response %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	parameter := regexp.MustCompile(`\{(\w+)\}`)
+	documented := map[string]bool{}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			documented[strings.ToUpper(method)+" "+parameter.ReplaceAllString(path, ":$1")] = true
+		}
+	}
+
+	var missing, undocumented []string
+	for route, versioned := range registered {
+		if !versioned {
+			t.Errorf("Legacy route %s has no %s counterpart", route, APIPrefixV1)
+		}
+		if !documented[route] {
+			missing = append(missing, route)
+		}
+	}
+	for route := range documented {
+		if _, ok := registered[route]; !ok {
+			undocumented = append(undocumented, route)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(undocumented)
+	if len(missing) > 0 {
+		t.Errorf("Routes missing from the OpenAPI specification: %v", missing)
+	}
+	if len(undocumented) > 0 {
+		t.Errorf("OpenAPI paths without registered routes: %v", undocumented)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"time"
+
+	"testProject/internal/model"
+)
+
+// CreatePersonRequest тело запроса создания человека.
+// Возраст, пол и национальность определяются обогащением, идентификатор, версия и время записи - сервером.
+type CreatePersonRequest struct {
+	Name       string `json:"name" xml:"name" yaml:"name"`
+	Surname    string `json:"surname" xml:"surname" yaml:"surname"`
+	Patronymic string `json:"patronymic" xml:"patronymic" yaml:"patronymic"`
+}
+
+// toModel возвращает человека для сервиса с полями из запроса.
+func (r CreatePersonRequest) toModel() model.Person {
+	return model.Person{Name: r.Name, Surname: r.Surname, Patronymic: r.Patronymic}
+}
+
+// UpdatePersonRequest тело запроса обновления человека и документ, к которому применяются патчи.
+// Поля, которыми владеет сервер, в запросе отсутствуют, поэтому клиент не может их изменить.
+type UpdatePersonRequest struct {
+	Name        string `json:"name" xml:"name" yaml:"name"`
+	Surname     string `json:"surname" xml:"surname" yaml:"surname"`
+	Patronymic  string `json:"patronymic" xml:"patronymic" yaml:"patronymic"`
+	Age         int    `json:"age" xml:"age" yaml:"age"`
+	Gender      string `json:"gender" xml:"gender" yaml:"gender"`
+	Nationality string `json:"nationality" xml:"nationality" yaml:"nationality"`
+}
+
+// newUpdatePersonRequest возвращает изменяемые клиентом поля person.
+func newUpdatePersonRequest(person *model.Person) UpdatePersonRequest {
+	return UpdatePersonRequest{
+		Name:        person.Name,
+		Surname:     person.Surname,
+		Patronymic:  person.Patronymic,
+		Age:         person.Age,
+		Gender:      person.Gender,
+		Nationality: person.Nationality,
+	}
+}
+
+// apply переносит поля запроса в person, не затрагивая поля сервера.
+func (r UpdatePersonRequest) apply(person *model.Person) {
+	person.Name, person.Surname, person.Patronymic = r.Name, r.Surname, r.Patronymic
+	person.Age, person.Gender, person.Nationality = r.Age, r.Gender, r.Nationality
+}
+
+// PersonResponse представление человека в ответах API.
+// CreatedAt и UpdatedAt отсутствуют у состояний, восстановленных по истории изменений,
+// EnrichedAt - у людей, данные которых не обогащались.
+// Метаданные обогащения ограничены временем EnrichedAt: источник и вероятность ответов Agify,
+// Genderize и Nationalize не сохраняются.
+type PersonResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Surname     string     `json:"surname"`
+	Patronymic  string     `json:"patronymic"`
+	Age         int        `json:"age"`
+	Gender      string     `json:"gender"`
+	Nationality string     `json:"nationality"`
+	Version     int        `json:"version"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	EnrichedAt  *time.Time `json:"enriched_at,omitempty"`
+}
+
+// newPersonResponse возвращает представление person для ответа.
+func newPersonResponse(person *model.Person) PersonResponse {
+	return PersonResponse{
+		ID:          person.ID,
+		Name:        person.Name,
+		Surname:     person.Surname,
+		Patronymic:  person.Patronymic,
+		Age:         person.Age,
+		Gender:      person.Gender,
+		Nationality: person.Nationality,
+		Version:     person.Version,
+		CreatedAt:   optionalTime(person.CreatedAt),
+		UpdatedAt:   optionalTime(person.UpdatedAt),
+		DeletedAt:   person.DeletedAt,
+		EnrichedAt:  person.EnrichedAt,
+	}
+}
+
+// newPeopleResponse возвращает представления people для ответа.
+func newPeopleResponse(people []model.Person) []PersonResponse {
+	response := make([]PersonResponse, len(people))
+	for i := range people {
+		response[i] = newPersonResponse(&people[i])
+	}
+	return response
+}
+
+// optionalTime возвращает nil для нулевого времени, чтобы оно не попадало в ответ.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPersonResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+
+	person := model.Person{Name: "Ivan", Surname: "Ivanov", Age: 30}
+	if err := repo.CreatePerson(context.Background(), &person, model.AuditInfo{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodGet, "/people", "")
+	var people []PersonResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &people); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if len(people) != 1 || people[0].ID != person.ID || people[0].Version != 1 || people[0].CreatedAt == nil || people[0].EnrichedAt != nil {
+		t.Errorf("Unexpected people %+v", people)
+	}
+
+	// Поля сервера в теле запроса игнорируются.
+	w = serve(http.MethodPut, "/people/1", `{"id":5,"version":9,"created_at":"2000-01-01T00:00:00Z","name":"Petr","surname":"Petrov"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d %q", w.Code, w.Body.String())
+	}
+	updated, err := repo.GetPersonById(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if updated.Name != "Petr" || updated.Version != 2 || !updated.CreatedAt.Equal(person.CreatedAt) {
+		t.Errorf("Unexpected updated person %+v", updated)
+	}
+}
+
+func TestApplyPatchIgnoresServerFields(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	person := model.Person{ID: 7, Name: "Ivan", Surname: "Ivanov", Version: 3, CreatedAt: createdAt}
+
+	err := applyPatch(&person, func(document []byte) ([]byte, error) {
+		return jsonpatch.MergePatch(document, []byte(`{"id":1,"version":1,"created_at":null,"name":"Petr"}`))
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if person.ID != 7 || person.Version != 3 || !person.CreatedAt.Equal(createdAt) || person.Name != "Petr" {
+		t.Errorf("Unexpected patch result: %+v", person)
+	}
+}
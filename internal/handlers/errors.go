@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus возвращает HTTP-статус для ошибки сервиса.
+// Несовпадение версии из If-Match - 412, остальные конфликты - 409, ошибки без типа - 500.
+// Чтение тела больше допустимого размера - 413, даже если сервис считает ошибку ошибкой данных.
+func errorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrUpstreamUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// respondError отвечает на запрос ошибкой application/problem+json со статусом из errorStatus и логирует ошибку.
+// Для ошибок валидации ответ перечисляет нарушенные правила полей на языке из Accept-Language. Клиенту возвращается текст ошибок 4xx, для 5xx текст может содержать подробности устройства сервиса,
+// поэтому вместо него возвращается message.
+func (h *Handler) respondError(c *gin.Context, err error, message string) {
+	writeError(c, h.logger, err, message)
+}
+
+// writeError отвечает на запрос ошибкой err так же, как respondError, и логирует ее в logger.
+// Используется в middleware, у которых нет Handler.
+func writeError(c *gin.Context, logger *logging.Logger, err error, message string) {
+	status := errorStatus(err)
+	switch {
+	case status == http.StatusPreconditionFailed:
+		message = "person version does not match If-Match header"
+	case status < http.StatusInternalServerError:
+		message = err.Error()
+	case status == http.StatusServiceUnavailable:
+		message = service.ErrUpstreamUnavailable.Error()
+	case status == http.StatusGatewayTimeout:
+		message = "request timed out"
+	}
+
+	if status >= http.StatusInternalServerError {
+		logger.Errorf("%s %s failed with %d: %v", c.Request.Method, c.FullPath(), status, err)
+	} else {
+		logger.Warnf("%s %s failed with %d: %v", c.Request.Method, c.FullPath(), status, err)
+	}
+	problem := newProblem(c, status, message)
+	var fieldErrs model.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		lang := requestLanguage(c)
+		problem.Detail = validationDetails[lang]
+		problem.Errors = fieldErrs.Localize(lang)
+		c.Header("Content-Language", lang)
+	}
+	writeProblem(c, status, problem)
+}
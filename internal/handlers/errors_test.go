@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestErrorStatus(t *testing.T) {
+	for _, test := range []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("person %w", service.ErrNotFound), http.StatusNotFound},
+		{service.ErrImportJobNotFound, http.StatusNotFound},
+		{fmt.Errorf("failed to delete person: %w", service.ErrConflict), http.StatusConflict},
+		{service.ErrVersionMismatch, http.StatusPreconditionFailed},
+		{fmt.Errorf("%w: name is required", service.ErrValidation), http.StatusUnprocessableEntity},
+		{errInvalidPatch, http.StatusUnprocessableEntity},
+		{fmt.Errorf("%w: Agify: timeout", service.ErrUpstreamUnavailable), http.StatusServiceUnavailable},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	} {
+		if status := errorStatus(test.err); status != test.status {
+			t.Errorf("Expected %d for %q, got %d", test.status, test.err, status)
+		}
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	hook := test.NewLocal(logger.Logger)
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	serve := func(method, target, body string) (*httptest.ResponseRecorder, Problem) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(RequestIDHeader, "request-1")
+		router.ServeHTTP(w, req)
+
+		var problem Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("Expected problem JSON, got %q", w.Body.String())
+		}
+		if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, ProblemContentType) {
+			t.Errorf("Expected %s, got %s", ProblemContentType, contentType)
+		}
+		return w, problem
+	}
+
+	hook.Reset()
+	w, problem := serve(http.MethodGet, "/people/999", "")
+	expected := Problem{
+		Type: "/problems/not-found", Title: "Not Found", Status: http.StatusNotFound,
+		Detail: "person not found", Instance: "/people/999", RequestID: "request-1",
+	}
+	if w.Code != http.StatusNotFound || !reflect.DeepEqual(problem, expected) {
+		t.Errorf("Unexpected not found problem %d %+v", w.Code, problem)
+	}
+	// Ошибку логирует только обработчик, сервис ее не дублирует.
+	var logged []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Level <= logrus.WarnLevel {
+			logged = append(logged, entry.Message)
+		}
+	}
+	if len(logged) != 1 {
+		t.Errorf("Expected the error to be logged once, got %q", logged)
+	}
+
+	w, problem = serve(http.MethodPost, "/people", `{"name":"Ivan","patronymic":"Ivan0vich"}`)
+	if w.Code != http.StatusUnprocessableEntity || problem.Type != "/problems/unprocessable-entity" {
+		t.Fatalf("Unexpected validation problem %d %+v", w.Code, problem)
+	}
+	expectedErrors := []model.FieldError{
+		{Field: "surname", Rule: "required", Message: "surname is required"},
+		{Field: "patronymic", Rule: "person_name", Message: "patronymic must contain only letters separated by single spaces, hyphens or apostrophes"},
+	}
+	if !reflect.DeepEqual(problem.Errors, expectedErrors) {
+		t.Errorf("Unexpected field errors %+v", problem.Errors)
+	}
+
+	w, problem = serve(http.MethodDelete, "/people", "")
+	if w.Code != http.StatusMethodNotAllowed || problem.Type != "/problems/method-not-allowed" || problem.RequestID != "request-1" {
+		t.Errorf("Unexpected method not allowed problem %d %+v", w.Code, problem)
+	}
+	w, problem = serve(http.MethodGet, "/unknown", "")
+	if w.Code != http.StatusNotFound || problem.Detail != "route not found" {
+		t.Errorf("Unexpected route not found problem %d %+v", w.Code, problem)
+	}
+	w, problem = serve(http.MethodGet, "/panic", "")
+	if w.Code != http.StatusInternalServerError || problem.Detail != "internal error" || problem.RequestID != "request-1" {
+		t.Errorf("Unexpected panic problem %d %+v", w.Code, problem)
+	}
+}
+
+func TestValidationLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/people", strings.NewReader(`{"name":"Ivan42","surname":"Ivanov"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.5")
+	router.ServeHTTP(w, req)
+
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusUnprocessableEntity || w.Header().Get("Content-Language") != "ru" || len(problem.Errors) != 1 {
+		t.Fatalf("Unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if problem.Errors[0].Rule != "person_name" || !strings.HasPrefix(problem.Errors[0].Message, "поле name должно") {
+		t.Errorf("Expected russian person_name message, got %+v", problem.Errors[0])
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formatETag возвращает сильный ETag для версии записи.
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// splitETags разбирает значение заголовков If-Match и If-None-Match на отдельные теги.
+func splitETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// matchesIfNoneMatch сообщает, совпадает ли etag с заголовком If-None-Match (слабое сравнение).
+func matchesIfNoneMatch(header, etag string) bool {
+	for _, tag := range splitETags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion возвращает версию записи из обязательного заголовка If-Match.
+// Для "*" возвращается 0, то есть версия не проверяется. Если заголовок отсутствует
+// или не может совпасть ни с одной версией, обработчик завершает запрос и возвращает false.
+func (h *Handler) ifMatchVersion(c *gin.Context) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		respondProblem(c, http.StatusPreconditionRequired, "If-Match header is required")
+		return 0, false
+	}
+
+	tags := splitETags(header)
+	for _, tag := range tags {
+		if tag == "*" {
+			return 0, true
+		}
+	}
+	if len(tags) != 1 {
+		respondProblem(c, http.StatusBadRequest, "If-Match header must contain a single entity tag")
+		return 0, false
+	}
+
+	// Слабые теги никогда не совпадают при сильном сравнении If-Match.
+	version, err := strconv.Atoi(strings.Trim(tags[0], `"`))
+	if err != nil || version <= 0 || formatETag(version) != tags[0] {
+		h.logger.Warnf("If-Match %q does not match any person version", header)
+		respondProblem(c, http.StatusPreconditionFailed, "person version does not match If-Match header")
+		return 0, false
+	}
+	return version, true
+}
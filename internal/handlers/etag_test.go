@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+
+	if err := repo.CreatePerson(context.Background(), &model.Person{Name: "Ivan", Surname: "Ivanov"}, model.AuditInfo{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	serve := func(method, contentType, body string, headers ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/v1/people/1", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	const update = `{"name":"Petr","surname":"Petrov"}`
+
+	t.Run("IfNoneMatch", func(t *testing.T) {
+		w := serve(http.MethodGet, "", "")
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+			t.Fatalf("Unexpected response %d with ETag %q", w.Code, w.Header().Get("ETag"))
+		}
+		for header, status := range map[string]int{
+			`"1"`:        http.StatusNotModified,
+			`W/"1"`:      http.StatusNotModified,
+			`"0", "1"`:   http.StatusNotModified,
+			`*`:          http.StatusNotModified,
+			`"2"`:        http.StatusOK,
+			`"1-stale"`:  http.StatusOK,
+			`W/"2", "3"`: http.StatusOK,
+		} {
+			w := serve(http.MethodGet, "", "", "If-None-Match", header)
+			if w.Code != status {
+				t.Errorf("If-None-Match %s: expected %d, got %d", header, status, w.Code)
+			}
+			if status == http.StatusNotModified && (w.Body.Len() != 0 || w.Header().Get("ETag") != `"1"`) {
+				t.Errorf("If-None-Match %s: expected empty 304 with ETag, got %q %q", header, w.Header().Get("ETag"), w.Body.String())
+			}
+		}
+	})
+
+	t.Run("IfMatchRequired", func(t *testing.T) {
+		for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if w := serve(method, MergePatchContentType, update); w.Code != http.StatusPreconditionRequired {
+				t.Errorf("%s without If-Match: expected 428, got %d", method, w.Code)
+			}
+		}
+		if w := serve(http.MethodPut, "application/json", update, "If-Match", `"1", "2"`); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for several entity tags, got %d", w.Code)
+		}
+	})
+
+	t.Run("VersionBump", func(t *testing.T) {
+		w := serve(http.MethodPut, "application/json", update, "If-Match", `"1"`)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+			t.Fatalf("Unexpected response %d with ETag %q: %s", w.Code, w.Header().Get("ETag"), w.Body.String())
+		}
+		if person, err := repo.GetPersonById(context.Background(), 1); err != nil || person.Version != 2 || person.Name != "Petr" {
+			t.Errorf("Expected updated person with version 2, got %+v %v", person, err)
+		}
+
+		w = serve(http.MethodPatch, MergePatchContentType, `{"age":40}`, "If-Match", `"2"`)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+			t.Errorf("Unexpected patch response %d with ETag %q: %s", w.Code, w.Header().Get("ETag"), w.Body.String())
+		}
+	})
+
+	t.Run("StaleIfMatch", func(t *testing.T) {
+		for _, tt := range []struct {
+			method, contentType, body, ifMatch string
+		}{
+			{http.MethodPut, "application/json", update, `"1"`},
+			{http.MethodPatch, MergePatchContentType, `{"age":41}`, `"2"`},
+			{http.MethodDelete, "", "", `"2"`},
+			// Слабый тег не совпадает при сильном сравнении даже с текущей версией.
+			{http.MethodPut, "application/json", update, `W/"3"`},
+		} {
+			if w := serve(tt.method, tt.contentType, tt.body, "If-Match", tt.ifMatch); w.Code != http.StatusPreconditionFailed {
+				t.Errorf("%s with If-Match %s: expected 412, got %d", tt.method, tt.ifMatch, w.Code)
+			}
+		}
+		if person, _ := repo.GetPersonById(context.Background(), 1); person.Version != 3 || person.Age != 40 {
+			t.Errorf("Expected person to stay at version 3, got %+v", person)
+		}
+
+		if w := serve(http.MethodDelete, "", "", "If-Match", `"3"`); w.Code != http.StatusOK {
+			t.Errorf("Expected 200 for current version, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"time"
+
+	"testProject/internal/exporter"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportPeople обработчик выгрузки людей файлом в формате csv, ndjson, xlsx или parquet из параметра format.
+// Фильтры, сортировка и include_deleted те же, что у списка людей, смещения и лимита нет.
+// Люди читаются из хранилища и пишутся в ответ потоком. Если выгрузка прервалась после начала ответа,
+// соединение обрывается, чтобы клиент не принял неполный файл за полный.
+func (h *Handler) ExportPeople(c *gin.Context) {
+	h.logger.Debug("Handling ExportPeople request")
+
+	format := c.DefaultQuery("format", exporter.FormatCSV)
+	if exporter.ContentType(format) == "" {
+		respondProblem(c, http.StatusBadRequest, "invalid format parameter, use csv, ndjson, xlsx or parquet")
+		return
+	}
+	filter, err := parsePeopleFilter(c)
+	if err != nil {
+		h.logger.Errorf("Failed to parse filter: %v", err)
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Ответ начинается до чтения людей, поэтому доступ к удаленным записям проверяется до него.
+	if filter.IncludeDeleted && !service.IsAdmin(c.Request.Context()) {
+		h.respondError(c, service.ErrForbidden, "failed to export people")
+		return
+	}
+
+	filename := "people-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+	c.Header("Content-Type", exporter.ContentType(format))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Status(http.StatusOK)
+
+	writer, err := exporter.NewWriter(format, c.Writer)
+	if err == nil {
+		err = h.service.ExportPeople(c.Request.Context(), filter, writer.Write)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	if !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		h.respondError(c, err, "failed to export people")
+		return
+	}
+	h.logger.Errorf("Failed to export people: %v", err)
+	abortStream(c)
+}
+
+// abortStream обрывает соединение с клиентом после частично отправленного ответа.
+func abortStream(c *gin.Context) {
+	c.Abort()
+	if conn, _, err := c.Writer.Hijack(); err == nil {
+		conn.Close()
+	}
+}
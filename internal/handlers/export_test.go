@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportPeople(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	for _, person := range []model.Person{{Name: "Ivan", Surname: "Ivanov", Age: 30}, {Name: "Anna", Surname: "Petrova", Age: 25}} {
+		if err := repo.CreatePerson(context.Background(), &person, model.AuditInfo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/people/export?format=csv&age=25", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment; filename=people-") || !strings.HasSuffix(disposition, ".csv") {
+		t.Errorf("Unexpected Content-Disposition %q", disposition)
+	}
+	anna, err := repo.GetPersonById(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdAt := anna.CreatedAt.UTC().Format(time.RFC3339)
+	expected := "id,name,surname,patronymic,age,gender,nationality,version,created_at,updated_at,deleted_at\n" +
+		"2,Anna,Petrova,,25,,,1," + createdAt + "," + createdAt + ",\n"
+	if w.Body.String() != expected {
+		t.Errorf("Unexpected export:\n%s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/people/export?format=pdf", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown format, got %d", w.Code)
+	}
+}
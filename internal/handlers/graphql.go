@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"testProject/internal/graphql"
+
+	"github.com/gin-gonic/gin"
+	gographql "github.com/graph-gophers/graphql-go"
+)
+
+// playgroundPage страница GraphQL Playground для запросов к /graphql.
+const playgroundPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="user-scalable=no, initial-scale=1.0, minimum-scale=1.0, maximum-scale=1.0, minimal-ui">
+  <title>People GraphQL</title>
+  <link rel="stylesheet" href="/graphql/playground.css">
+  <script src="/graphql/playground.js"></script>
+</head>
+<body>
+  <div id="root"></div>
+  <script>
+    window.addEventListener("load", function () {
+      GraphQLPlayground.init(document.getElementById("root"), {endpoint: "/graphql"});
+    });
+  </script>
+</body>
+</html>
+`
+
+// graphQLRequest тело запроса GraphQL.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// RegisterGraphQLRoutes регистрирует POST /graphql для запросов к schema, а с playground - GET /graphql
+// со страницей GraphQL Playground, скрипты и стили которой встроены в приложение. Изменения записываются в историю от имени X-Actor, как и в REST API.
+// Вызывается после RegisterRoutes, чтобы запросы проходили ту же цепочку middleware: идентификатор запроса,
+// ограничение времени (ключ "POST /graphql" в routeTimeouts) и выбор реплики для чтения.
+func RegisterGraphQLRoutes(router *gin.Engine, schema *gographql.Schema, playground bool) {
+	router.POST("/graphql", func(c *gin.Context) {
+		var request graphQLRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondProblem(c, http.StatusBadRequest, "invalid request payload")
+			return
+		}
+		ctx := graphql.WithAudit(c.Request.Context(), auditInfo(c))
+		c.JSON(http.StatusOK, schema.Exec(ctx, request.Query, request.OperationName, request.Variables))
+	})
+
+	if playground {
+		router.GET("/graphql", func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(playgroundPage))
+		})
+		router.GET("/graphql/playground.js", assetHandler("graphql-playground.js"))
+		router.GET("/graphql/playground.css", assetHandler("graphql-playground.css"))
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/internal/graphql"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGraphQLRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	service := service.NewService(repo, logger)
+	schema, err := graphql.NewSchema(service, logger)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		playground bool
+		method     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"query", false, http.MethodPost, `{"query":"{ people { edges { node { id } } pageInfo { hasNextPage } } }"}`, http.StatusOK, `"hasNextPage":false`},
+		{"query error", false, http.MethodPost, `{"query":"{ person(id: \"x\") { id } }"}`, http.StatusOK, `"code":"BAD_USER_INPUT"`},
+		{"invalid payload", false, http.MethodPost, `{`, http.StatusBadRequest, "invalid request payload"},
+		{"playground", true, http.MethodGet, "", http.StatusOK, "GraphQLPlayground.init"},
+		{"playground disabled", false, http.MethodGet, "", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			RegisterRoutes(router, service, repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+			RegisterGraphQLRoutes(router, schema, tt.playground)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, "/graphql", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(RequestIDHeader, "graphql-request")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("Unexpected response %d %q", w.Code, w.Body.String())
+			}
+			// Маршруты GraphQL проходят общую цепочку middleware из RegisterRoutes.
+			if w.Code != http.StatusNotFound && w.Header().Get(RequestIDHeader) != "graphql-request" {
+				t.Errorf("Expected request ID to be echoed, got %q", w.Header().Get(RequestIDHeader))
+			}
+		})
+	}
+
+	t.Run("playground assets", func(t *testing.T) {
+		router := gin.New()
+		RegisterGraphQLRoutes(router, schema, true)
+		for path, contentType := range map[string]string{"/graphql/playground.js": "text/javascript", "/graphql/playground.css": "text/css"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), contentType) || w.Body.Len() == 0 {
+				t.Errorf("%s: unexpected response %d %q", path, w.Code, w.Header().Get("Content-Type"))
+			}
+		}
+	})
+}
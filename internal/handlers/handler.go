@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"testProject/internal/model"
 	"testProject/pkg/logging"
 	"testProject/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +18,10 @@ import (
 type Handler struct {
 	service *service.Service
 	logger  *logging.Logger
+	// maxImportSize наибольший размер загрузки импорта в байтах.
+	maxImportSize int64
+	// idempotency сохраняет ответы на импорт с ключом идемпотентности, хеш которого вычисляется по загрузке.
+	idempotency *idempotencyGuard
 }
 
 // NewHandler принимает service и logger в конструкторе и возрашает cтруктуру *Handler.
@@ -21,117 +29,256 @@ func NewHandler(service service.Service, logger *logging.Logger) *Handler {
 	return &Handler{service: &service, logger: logger}
 }
 
-// CreatePerson обработчик создания нового человека.
+// CreatePerson обработчик создания нового человека. Отвечает созданной записью вместе с ее идентификатором.
 func (h *Handler) CreatePerson(c *gin.Context) {
 	h.logger.Debug("Handling CreatePerson request")
-	var input model.Person
-	if err := c.ShouldBindJSON(&input); err != nil {
-		h.logger.Fatalf("Failed to bind JSON: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+	var request CreatePersonRequest
+	if !h.bindBody(c, &request) {
 		return
 	}
+	input := request.toModel()
 
-	if err := h.service.CreatePerson(&input); err != nil {
-		h.logger.Errorf("Failed to create person: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create person"})
+	if err := h.service.CreatePerson(c.Request.Context(), &input, auditInfo(c)); err != nil {
+		h.respondError(c, err, "failed to create person")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"id": input.ID})
+	c.Header("ETag", formatETag(input.Version))
+	render(c, http.StatusCreated, "person", newPersonResponse(&input))
 }
 
 // GetPeople обработчик получения списка людей.
+// Параметр sort задает поля сортировки через запятую, префикс "-" означает сортировку по убыванию.
+// Удаленные записи возвращаются только с параметром include_deleted=true.
 func (h *Handler) GetPeople(c *gin.Context) {
 	h.logger.Debug("Handling GetPeople request")
-	filters := make(map[string]interface{})
-	for key, value := range c.Request.URL.Query() {
-		if len(value) > 0 {
-			filters[key] = value[0]
-		}
+	filter, err := parsePeopleFilter(c)
+	if err != nil {
+		h.logger.Errorf("Failed to parse filter: %v", err)
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
 	if err != nil {
 		h.logger.Errorf("Failed to parse offset: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset parameter"})
+		respondProblem(c, http.StatusBadRequest, "invalid offset parameter")
 		return
 	}
 
 	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	if err != nil {
 		h.logger.Errorf("Failed to parse limit: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		respondProblem(c, http.StatusBadRequest, "invalid limit parameter")
 		return
 	}
-	people, err := h.service.GetPeople(filters, offset, limit)
+	filter.Offset, filter.Limit = offset, limit
+
+	people, err := h.service.GetPeople(c.Request.Context(), filter)
 	if err != nil {
-		h.logger.Errorf("Failed to get people: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get people"})
+		h.respondError(c, err, "failed to get people")
 		return
 	}
-	c.JSON(http.StatusOK, people)
+	render(c, http.StatusOK, "people", newPeopleResponse(people))
+}
+
+// parsePeopleFilter разбирает параметры фильтрации, сортировки и include_deleted списка людей.
+// Текст ошибки можно вернуть клиенту.
+func parsePeopleFilter(c *gin.Context) (model.PersonFilter, error) {
+	filter := model.PersonFilter{Fields: make(map[string]string)}
+	for _, field := range model.PersonFilterFields {
+		if value, ok := c.GetQuery(field); ok {
+			filter.Fields[field] = value
+		}
+	}
+	if age, ok := filter.Fields["age"]; ok {
+		if _, err := strconv.Atoi(age); err != nil {
+			return filter, errors.New("invalid age parameter")
+		}
+	}
+
+	includeDeleted, err := strconv.ParseBool(c.DefaultQuery("include_deleted", "false"))
+	if err != nil {
+		return filter, errors.New("invalid include_deleted parameter")
+	}
+	filter.IncludeDeleted = includeDeleted
+
+	if filter.Sort, err = parseSort(c.Query("sort")); err != nil {
+		return filter, errors.New("invalid sort parameter")
+	}
+	return filter, nil
+}
+
+// parseSort разбирает параметр sort вида "-age,name" в поля сортировки.
+func parseSort(value string) ([]model.SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []model.SortField
+	for _, part := range strings.Split(value, ",") {
+		field := model.SortField{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(field.Field, "-") {
+			field.Field, field.Desc = field.Field[1:], true
+		}
+		if !isSortField(field.Field) {
+			return nil, fmt.Errorf("unknown sort field %q", field.Field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// isSortField сообщает, можно ли сортировать список людей по полю field.
+func isSortField(field string) bool {
+	for _, sortField := range model.PersonSortFields {
+		if sortField == field {
+			return true
+		}
+	}
+	return false
 }
 
 // GetPersonById обработчик получения информации о человеке по идентификатору.
+// Возвращает версию записи в заголовке ETag и 304 Not Modified, если она совпадает с If-None-Match.
+// С параметром as_of (RFC 3339) возвращает состояние записи на этот момент по истории изменений.
 func (h *Handler) GetPersonById(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		h.logger.Errorf("Failed to parse person ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person ID"})
+		respondProblem(c, http.StatusBadRequest, "invalid person ID")
+		return
+	}
+
+	if value, ok := c.GetQuery("as_of"); ok {
+		h.getPersonAsOf(c, id, value)
 		return
 	}
 
-	persone, err := h.service.GetPersonById(id)
+	persone, err := h.service.GetPersonById(c.Request.Context(), id)
 	if err != nil {
-		h.logger.Errorf("Failed to get person by ID: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get person by ID"})
+		h.respondError(c, err, "failed to get person by ID")
 		return
 	}
-	c.JSON(http.StatusOK, persone)
+
+	etag := formatETag(persone.Version)
+	c.Header("ETag", etag)
+	if matchesIfNoneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	render(c, http.StatusOK, "person", newPersonResponse(persone))
 
 }
 
 // UpdatePerson обработчик обновления информации о человеке.
+// Требует заголовок If-Match с текущим ETag записи и возвращает 412, если запись уже изменили.
 func (h *Handler) UpdatePerson(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.Fatalf("Failed to parse person ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person ID"})
+		h.logger.Errorf("Failed to parse person ID: %v", err)
+		respondProblem(c, http.StatusBadRequest, "invalid person ID")
 		return
 	}
 
-	var input model.Person
+	version, ok := h.ifMatchVersion(c)
+	if !ok {
+		return
+	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		h.logger.Errorf("Failed to bind JSON: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+	var request UpdatePersonRequest
+	if !h.bindBody(c, &request) {
+		return
+	}
+	input := model.Person{ID: uint(id), Version: version}
+	request.apply(&input)
+	if !h.validatePerson(c, &input) {
 		return
 	}
-	input.ID = uint(id)
 
-	if err := h.service.UpdatePerson(&input); err != nil {
-		h.logger.Errorf("Failed to update person: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update person"})
+	if err := h.service.UpdatePerson(c.Request.Context(), &input, auditInfo(c)); err != nil {
+		h.respondError(c, err, "failed to update person")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "person updated successfully"})
+	c.Header("ETag", formatETag(input.Version))
+	render(c, http.StatusOK, "response", gin.H{"message": "person updated successfully"})
 }
 
 // DeletePerson обработчик удаления информации о человеке.
+// Требует заголовок If-Match с текущим ETag записи и возвращает 412, если запись уже изменили.
 func (h *Handler) DeletePerson(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.Fatalf("Failed to parse person ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person ID"})
+		h.logger.Errorf("Failed to parse person ID: %v", err)
+		respondProblem(c, http.StatusBadRequest, "invalid person ID")
+		return
+	}
+
+	version, ok := h.ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePerson(c.Request.Context(), id, version, auditInfo(c)); err != nil {
+		h.respondError(c, err, "failed to delete person")
+		return
+	}
+	render(c, http.StatusOK, "response", gin.H{"message": "person deleted successfully"})
+
+}
+
+// RestorePerson обработчик восстановления удаленной информации о человеке.
+func (h *Handler) RestorePerson(c *gin.Context) {
+	h.logger.Debug("Handling RestorePerson request")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("Failed to parse person ID: %v", err)
+		respondProblem(c, http.StatusBadRequest, "invalid person ID")
+		return
+	}
+
+	person, err := h.service.RestorePerson(c.Request.Context(), id, auditInfo(c))
+	if err != nil {
+		h.respondError(c, err, "failed to restore person")
+		return
+	}
+
+	c.Header("ETag", formatETag(person.Version))
+	render(c, http.StatusOK, "person", newPersonResponse(person))
+}
+
+// getPersonAsOf отвечает состоянием человека с идентификатором id на момент asOf.
+func (h *Handler) getPersonAsOf(c *gin.Context, id int, asOf string) {
+	moment, err := time.Parse(time.RFC3339, asOf)
+	if err != nil {
+		h.logger.Errorf("Failed to parse as_of: %v", err)
+		respondProblem(c, http.StatusBadRequest, "invalid as_of parameter")
+		return
+	}
+
+	person, err := h.service.GetPersonAsOf(c.Request.Context(), id, moment)
+	if err != nil {
+		h.respondError(c, err, "failed to get person by ID")
 		return
 	}
+	render(c, http.StatusOK, "person", newPersonResponse(person))
+}
 
-	if err := h.service.DeletePerson(id); err != nil {
-		h.logger.Fatalf("Failed to delete person: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete person"})
+// GetPersonHistory обработчик получения истории изменений человека.
+func (h *Handler) GetPersonHistory(c *gin.Context) {
+	h.logger.Debug("Handling GetPersonHistory request")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("Failed to parse person ID: %v", err)
+		respondProblem(c, http.StatusBadRequest, "invalid person ID")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "person deleted successfully"})
 
+	changes, err := h.service.GetPersonHistory(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "failed to get person history")
+		return
+	}
+	render(c, http.StatusOK, "changes", changes)
 }
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSoftDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	router.Use(AdminAuth("secret"))
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+
+	for _, person := range []model.Person{{Name: "Ivan", Surname: "Ivanov"}, {Name: "Anna", Surname: "Ivanova"}} {
+		if err := repo.CreatePerson(context.Background(), &person, model.AuditInfo{}); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+	}
+
+	serve := func(method, target, authorization string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("If-Match", "*")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	people := func(target, authorization string) []PersonResponse {
+		w := serve(http.MethodGet, target, authorization)
+		var response []PersonResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected response %d %s", target, w.Code, w.Body.String())
+		}
+		return response
+	}
+
+	if w := serve(http.MethodDelete, "/api/v1/people/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(http.MethodGet, "/api/v1/people/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected deleted person to be hidden, got %d", w.Code)
+	}
+	if list := people("/api/v1/people", ""); len(list) != 1 || list[0].Name != "Anna" {
+		t.Errorf("Expected only Anna without deleted people, got %+v", list)
+	}
+
+	// Удаленные записи и восстановление доступны только администратору.
+	for _, authorization := range []string{"", "Bearer wrong", "secret"} {
+		if w := serve(http.MethodGet, "/api/v1/people?include_deleted=true", authorization); w.Code != http.StatusForbidden {
+			t.Errorf("%q: expected 403 for deleted people, got %d", authorization, w.Code)
+		}
+		if w := serve(http.MethodGet, "/api/v1/people/export?include_deleted=true", authorization); w.Code != http.StatusForbidden {
+			t.Errorf("%q: expected 403 for deleted people export, got %d", authorization, w.Code)
+		}
+		if w := serve(http.MethodPost, "/api/v1/people/1/restore", authorization); w.Code != http.StatusForbidden {
+			t.Errorf("%q: expected 403 for restore, got %d", authorization, w.Code)
+		}
+	}
+
+	list := people("/api/v1/people?include_deleted=true&sort=id", "Bearer secret")
+	if len(list) != 2 || list[0].DeletedAt == nil || list[1].DeletedAt != nil {
+		t.Errorf("Expected deleted Ivan and Anna, got %+v", list)
+	}
+
+	w := serve(http.MethodPost, "/api/v1/people/1/restore", "Bearer secret")
+	var restored PersonResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &restored); err != nil || w.Code != http.StatusOK || restored.DeletedAt != nil || restored.Version != 3 {
+		t.Fatalf("Unexpected restore response %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != `"3"` {
+		t.Errorf("Unexpected ETag %q", w.Header().Get("ETag"))
+	}
+	if w := serve(http.MethodPost, "/api/v1/people/1/restore", "Bearer secret"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for not deleted person, got %d", w.Code)
+	}
+	if list := people("/api/v1/people", ""); len(list) != 2 {
+		t.Errorf("Expected restored person in the list, got %+v", list)
+	}
+}
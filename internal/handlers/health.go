@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"testProject/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pinger хранилище, доступность которого проверяет /readyz.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// DBStatsProvider хранилище со статистикой пулов соединений по роли базы данных, например "primary" и "replica_0".
+type DBStatsProvider interface {
+	Stats() map[string]sql.DBStats
+}
+
+// RegisterHealthRoutes регистрирует /healthz (процесс работает) и /readyz (хранилище доступно) для проб Kubernetes.
+// Если storage предоставляет статистику пулов соединений, она доступна администратору по /admin/db/stats,
+// поэтому AdminAuth должен быть подключен к router раньше.
+func RegisterHealthRoutes(router *gin.Engine, storage Pinger) {
+	logger := logging.GetLogger()
+
+	router.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	router.GET("/readyz", func(c *gin.Context) {
+		if err := storage.Ping(c.Request.Context()); err != nil {
+			logger.Warnf("Readiness check failed: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	if provider, ok := storage.(DBStatsProvider); ok {
+		router.GET("/admin/db/stats", RequireAdmin(), func(c *gin.Context) {
+			pools := gin.H{}
+			for name, stats := range provider.Stats() {
+				pools[name] = dbStatsResponse(stats)
+			}
+			c.JSON(http.StatusOK, pools)
+		})
+	}
+}
+
+// dbStatsResponse представляет статистику пула в JSON с именами полей в snake_case.
+func dbStatsResponse(stats sql.DBStats) gin.H {
+	return gin.H{
+		"max_open_connections": stats.MaxOpenConnections,
+		"open_connections":     stats.OpenConnections,
+		"in_use":               stats.InUse,
+		"idle":                 stats.Idle,
+		"wait_count":           stats.WaitCount,
+		"wait_duration":        stats.WaitDuration.String(),
+		"max_idle_closed":      stats.MaxIdleClosed,
+		"max_idle_time_closed": stats.MaxIdleTimeClosed,
+		"max_lifetime_closed":  stats.MaxLifetimeClosed,
+	}
+}
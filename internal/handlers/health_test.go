@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeStorage struct {
+	err error
+}
+
+func (s *fakeStorage) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func (s *fakeStorage) Stats() map[string]sql.DBStats {
+	return map[string]sql.DBStats{
+		"primary":   {MaxOpenConnections: 10, OpenConnections: 3, InUse: 1, Idle: 2},
+		"replica_0": {MaxOpenConnections: 10, OpenConnections: 1, Idle: 1},
+	}
+}
+
+func TestHealthRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage := &fakeStorage{}
+	router := gin.New()
+	router.Use(AdminAuth("secret"))
+	RegisterHealthRoutes(router, storage)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := get("/readyz"); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for available storage, got %d", w.Code)
+	}
+	if w := get("/admin/db/stats"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 without admin token, got %d", w.Code)
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/db/stats", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(w, req)
+	var pools map[string]struct {
+		OpenConnections int `json:"open_connections"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &pools); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected pool statistics, got %d %q", w.Code, w.Body.String())
+	}
+	if pools["primary"].OpenConnections != 3 || pools["replica_0"].OpenConnections != 1 {
+		t.Errorf("Expected statistics of every pool, got %+v", pools)
+	}
+
+	storage.err = errors.New("connection refused")
+	if w := get("/readyz"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for unavailable storage, got %d", w.Code)
+	}
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 from liveness probe, got %d", w.Code)
+	}
+}
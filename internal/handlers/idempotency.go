@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader заголовок, которым клиент помечает повторяемый запрос.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyStore хранилище ответов на запросы с ключом идемпотентности.
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (*model.IdempotencyRecord, bool, error)
+	SaveIdempotencyResponse(ctx context.Context, key string, statusCode int, contentType string, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// responseRecorder дублирует тело ответа в буфер, чтобы его можно было сохранить.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency middleware для изменяющих запросов с заголовком Idempotency-Key.
+// Первый ответ (статус и тело) сохраняется в store на время ttl, повтор с тем же ключом, телом и форматом
+// ответа, выбранным Negotiation, возвращает сохраненный ответ, а повтор с другим телом или форматом
+// завершается статусом 422.
+// Ответы 5xx не сохраняются, чтобы клиент мог повторить запрос.
+// Ответ сохраняется вне контекста запроса, чтобы отключение клиента не помешало повтору.
+// Пока запрос обрабатывается, повтор получает 409. Если ответ не сохранен за lease, например процесс
+// остановился посреди запроса, повтор выполняет запрос заново, поэтому lease должна превышать время обработки.
+func Idempotency(store IdempotencyStore, ttl, lease time.Duration, logger *logging.Logger) gin.HandlerFunc {
+	return newIdempotencyGuard(store, ttl, lease, logger).middleware
+}
+
+// idempotencyGuard сохраняет и повторяет ответы на запросы с ключом идемпотентности.
+// Кроме middleware используется обработчиками, которые сами вычисляют хеш запроса, например импортом.
+type idempotencyGuard struct {
+	store      IdempotencyStore
+	ttl, lease time.Duration
+	logger     *logging.Logger
+}
+
+func newIdempotencyGuard(store IdempotencyStore, ttl, lease time.Duration, logger *logging.Logger) *idempotencyGuard {
+	return &idempotencyGuard{store: store, ttl: ttl, lease: lease, logger: logger}
+}
+
+func (g *idempotencyGuard) middleware(c *gin.Context) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key == "" || !isMutatingMethod(c.Request.Method) {
+		c.Next()
+		return
+	}
+	if !validIdempotencyKey(c, key) {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		g.logger.Errorf("Failed to read request body: %v", err)
+		respondProblem(c, http.StatusBadRequest, "invalid request payload")
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	g.run(c, key, hashRequest(c.Request.Method, idempotencyTarget(c), c.GetString(responseMediaTypeKey), body), c.Next)
+}
+
+// run резервирует key для запроса с хешем requestHash и выполняет next, сохраняя его ответ.
+// Повтор запроса получает сохраненный ответ без вызова next, а запрос с другим хешем или во время
+// обработки первого - ответ 422 или 409.
+func (g *idempotencyGuard) run(c *gin.Context, key, requestHash string, next func()) {
+	record, reserved, err := g.store.ReserveIdempotencyKey(c.Request.Context(), key, requestHash, g.ttl, g.lease)
+	if err != nil {
+		g.logger.Errorf("Failed to reserve idempotency key: %v", err)
+		respondProblem(c, http.StatusInternalServerError, "failed to process idempotency key")
+		return
+	}
+
+	if !reserved {
+		switch {
+		case record.RequestHash != requestHash:
+			respondProblem(c, http.StatusUnprocessableEntity, "idempotency key was already used with a different request")
+		case record.StatusCode == 0:
+			respondProblem(c, http.StatusConflict, "request with this idempotency key is still in progress")
+		default:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.StatusCode, record.ContentType, record.Body)
+			c.Abort()
+		}
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+	c.Writer = recorder
+
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		// Обработчик запаниковал: освобождаем ключ, панику обработает Recovery.
+		g.release(key)
+	}()
+
+	next()
+	completed = true
+
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError {
+		g.release(key)
+		return
+	}
+
+	if err := g.store.SaveIdempotencyResponse(context.Background(), key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+		g.logger.Errorf("Failed to save idempotent response: %v", err)
+	}
+}
+
+func (g *idempotencyGuard) release(key string) {
+	if err := g.store.ReleaseIdempotencyKey(context.Background(), key); err != nil {
+		g.logger.Errorf("Failed to release idempotency key: %v", err)
+	}
+}
+
+// validIdempotencyKey проверяет длину ключа и отвечает 400 на слишком длинный ключ.
+func validIdempotencyKey(c *gin.Context, key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		respondProblem(c, http.StatusBadRequest, "idempotency key is too long")
+		return false
+	}
+	return true
+}
+
+// idempotencyLeaseMargin запас аренды ключа сверх времени обработки запроса на сохранение ответа.
+const idempotencyLeaseMargin = 10 * time.Second
+
+// defaultIdempotencyLease аренда ключа, если время обработки запроса не ограничено.
+const defaultIdempotencyLease = 10 * time.Minute
+
+// idempotencyLease возвращает аренду ключа идемпотентности, которая превышает время обработки самого долгого
+// маршрута: requestTimeout или значения из routeTimeouts. Неограниченное время заменяется defaultIdempotencyLease.
+func idempotencyLease(requestTimeout time.Duration, routeTimeouts map[string]time.Duration) time.Duration {
+	longest := requestTimeout
+	unlimited := requestTimeout <= 0
+	for _, timeout := range routeTimeouts {
+		unlimited = unlimited || timeout <= 0
+		if timeout > longest {
+			longest = timeout
+		}
+	}
+	lease := longest + idempotencyLeaseMargin
+	if unlimited && lease < defaultIdempotencyLease {
+		return defaultIdempotencyLease
+	}
+	return lease
+}
+
+// isMutatingMethod сообщает, изменяет ли запрос с этим методом данные.
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// idempotencyTarget возвращает путь запроса без префикса версии API и параметры запроса в порядке имен:
+// запрос по прежнему пути и с префиксом APIPrefixV1 - один и тот же запрос.
+func idempotencyTarget(c *gin.Context) string {
+	return unversionedPath(c.Request.URL.Path) + "?" + c.Request.URL.Query().Encode()
+}
+
+// hashRequest возвращает хеш запроса, по которому повтор отличается от нового запроса с тем же ключом.
+// target - путь с параметрами запроса, mediaType - тип содержимого ответа: сохраненный ответ можно вернуть
+// только в том же формате.
+func hashRequest(method, target, mediaType string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{' '})
+	h.Write([]byte(target))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(mediaType))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
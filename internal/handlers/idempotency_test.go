@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*model.IdempotencyRecord
+}
+
+func (s *memoryIdempotencyStore) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (*model.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockedUntil := time.Now().Add(lease)
+	if record, ok := s.records[key]; ok {
+		if record.StatusCode == 0 && record.RequestHash == requestHash && record.LockedUntil.Before(time.Now()) {
+			record.LockedUntil = &lockedUntil
+			return nil, true, nil
+		}
+		copied := *record
+		return &copied, false, nil
+	}
+	s.records[key] = &model.IdempotencyRecord{Key: key, RequestHash: requestHash, ExpiresAt: time.Now().Add(ttl), LockedUntil: &lockedUntil}
+	return nil, true, nil
+}
+
+func (s *memoryIdempotencyStore) SaveIdempotencyResponse(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[key]
+	record.StatusCode, record.ContentType, record.Body = statusCode, contentType, body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	status := http.StatusCreated
+	router := gin.New()
+	store := &memoryIdempotencyStore{records: map[string]*model.IdempotencyRecord{}}
+	router.POST("/people", Negotiation(false), Idempotency(store, time.Hour, time.Minute, logging.GetLogger()), func(c *gin.Context) {
+		calls++
+		render(c, status, "person", gin.H{"id": calls})
+	})
+
+	accept := ""
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/people", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("key-1", `{"name":"Ivan"}`)
+	replay := send("key-1", `{"name":"Ivan"}`)
+	if first.Code != http.StatusCreated || replay.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 for first request and replay, got %d and %d", first.Code, replay.Code)
+	}
+	if replay.Body.String() != first.Body.String() || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected replay of %q, got %q", first.Body.String(), replay.Body.String())
+	}
+	if calls != 1 {
+		t.Errorf("Expected handler to be called once, got %d", calls)
+	}
+
+	if w := send("key-1", `{"name":"Petr"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a different body, got %d", w.Code)
+	}
+	// Сохраненный ответ в JSON нельзя вернуть на повтор, который ждет другой формат.
+	accept = "application/xml"
+	if w := send("key-1", `{"name":"Ivan"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a different Accept, got %d", w.Code)
+	}
+	accept = ""
+
+	send("", `{"name":"Ivan"}`)
+	if calls != 2 {
+		t.Errorf("Expected request without key to reach handler, got %d calls", calls)
+	}
+
+	status = http.StatusInternalServerError
+	send("key-2", `{"name":"Ivan"}`)
+	status = http.StatusCreated
+	if w := send("key-2", `{"name":"Ivan"}`); w.Code != http.StatusCreated || calls != 4 {
+		t.Errorf("Expected failed request to be retried, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotencyLease(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Первый запрос не завершается, как если бы процесс остановился посреди обработки.
+	started, release := make(chan struct{}), make(chan struct{})
+	calls := 0
+	router := gin.New()
+	store := &memoryIdempotencyStore{records: map[string]*model.IdempotencyRecord{}}
+	router.POST("/people", Idempotency(store, time.Hour, 50*time.Millisecond, logging.GetLogger()), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-release
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/people", strings.NewReader(`{"name":"Ivan"}`))
+		req.Header.Set(IdempotencyKeyHeader, "key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	stuck := make(chan struct{})
+	go func() {
+		defer close(stuck)
+		send()
+	}()
+	defer func() {
+		close(release)
+		<-stuck
+	}()
+	<-started
+
+	if w := send(); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 while the first request holds the key, got %d", w.Code)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if w := send(); w.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("Expected retry to take over the expired key, got %d after %d calls", w.Code, calls)
+	}
+	if w := send(); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" || calls != 2 {
+		t.Errorf("Expected replay of the saved response, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotencyTarget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	router := gin.New()
+	store := &memoryIdempotencyStore{records: map[string]*model.IdempotencyRecord{}}
+	handler := func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	}
+	router.POST("/people", Idempotency(store, time.Hour, time.Minute, logging.GetLogger()), handler)
+	router.POST(APIPrefixV1+"/people", Idempotency(store, time.Hour, time.Minute, logging.GetLogger()), handler)
+
+	send := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"name":"Ivan"}`))
+		req.Header.Set(IdempotencyKeyHeader, "key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("/people?a=1&b=2"); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", w.Code)
+	}
+	// Префикс версии и порядок параметров не меняют запрос.
+	if w := send(APIPrefixV1 + "/people?b=2&a=1"); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected replay through the versioned path, got %d %v", w.Code, w.Header())
+	}
+	if w := send("/people?a=1&b=3"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a different query, got %d", w.Code)
+	}
+	if calls != 1 {
+		t.Errorf("Expected handler to be called once, got %d", calls)
+	}
+}
+
+func TestIdempotencyLeaseDuration(t *testing.T) {
+	tests := []struct {
+		requestTimeout time.Duration
+		routeTimeouts  map[string]time.Duration
+		want           time.Duration
+	}{
+		{30 * time.Second, nil, 30*time.Second + idempotencyLeaseMargin},
+		{30 * time.Second, map[string]time.Duration{"POST /people/import": 5 * time.Minute}, 5*time.Minute + idempotencyLeaseMargin},
+		{0, nil, defaultIdempotencyLease},
+		{time.Second, map[string]time.Duration{"POST /people/import": 0}, defaultIdempotencyLease},
+	}
+	for _, tt := range tests {
+		if got := idempotencyLease(tt.requestTimeout, tt.routeTimeouts); got != tt.want {
+			t.Errorf("idempotencyLease(%v, %v): expected %v, got %v", tt.requestTimeout, tt.routeTimeouts, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"testProject/internal/importer"
+	"testProject/internal/model"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSyncImportSize наибольший размер загрузки, которая импортируется в рамках запроса.
+// Загрузки больше или неизвестного размера импортируются в фоне.
+const maxSyncImportSize = 1 << 20
+
+// importFormats форматы импорта по Content-Type загрузки.
+var importFormats = map[string]string{
+	"text/csv":             importer.FormatCSV,
+	"application/x-ndjson": importer.FormatNDJSON,
+	"application/jsonl":    importer.FormatNDJSON,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": importer.FormatXLSX,
+}
+
+// ImportPeople обработчик массового импорта людей из CSV, NDJSON или XLSX.
+// Файл передается телом запроса или полем file в multipart/form-data, формат определяется параметром format,
+// Content-Type или расширением файла. Параметры mapping[Колонка]=поле сопоставляют колонки полям человека,
+// enrich=true заполняет пустые возраст, пол и национальность через внешние сервисы.
+// Небольшие загрузки возвращают отчет об импорте сразу, большие и с async=true импортируются в фоне:
+// ответ 202 содержит задачу, состояние которой доступно по адресу из заголовка Location.
+// Загрузка больше maxImportSize отклоняется с 413.
+// С заголовком Idempotency-Key загрузка сохраняется во временный файл, хеш запроса вычисляется по ее
+// содержимому при сохранении, и повтор получает сохраненный ответ до запуска импорта.
+func (h *Handler) ImportPeople(c *gin.Context) {
+	h.logger.Debug("Handling ImportPeople request")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxImportSize)
+
+	enrich, err := strconv.ParseBool(c.DefaultQuery("enrich", "false"))
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid enrich parameter")
+		return
+	}
+	async, err := strconv.ParseBool(c.DefaultQuery("async", "false"))
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid async parameter")
+		return
+	}
+	opts := service.ImportOptions{Enrich: enrich}
+	sync := !async && c.Request.ContentLength >= 0 && c.Request.ContentLength <= maxSyncImportSize
+
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key != "" && !validIdempotencyKey(c, key) {
+		return
+	}
+
+	body, format, err := importBody(c)
+	if err != nil {
+		h.logger.Errorf("Failed to read import upload: %v", err)
+		respondProblem(c, uploadErrorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+	if format == "" {
+		respondProblem(c, http.StatusUnsupportedMediaType, "unsupported import format, use csv, ndjson or xlsx")
+		return
+	}
+
+	if key == "" || h.idempotency == nil {
+		h.importUpload(c, body, format, sync, opts)
+		return
+	}
+
+	digest := sha256.New()
+	file, ok := h.spoolImport(c, io.TeeReader(body, digest))
+	if !ok {
+		return
+	}
+	// Файл удаляется после ответа, если его не забрал фоновый импорт: повтор с сохраненным ответом
+	// или конфликтом импорт не запускает.
+	started := false
+	defer func() {
+		if !started {
+			removeUpload(file)
+		}
+	}()
+	upload := format + "\n" + hex.EncodeToString(digest.Sum(nil))
+	requestHash := hashRequest(c.Request.Method, idempotencyTarget(c), c.GetString(responseMediaTypeKey), []byte(upload))
+	h.idempotency.run(c, key, requestHash, func() {
+		started = !sync
+		h.importUpload(c, file, format, sync, opts)
+	})
+}
+
+// importUpload импортирует body в рамках запроса, если sync, или запускает фоновый импорт.
+// Фоновый импорт читает body из временного файла и удаляет его по завершении: если body уже файл
+// от spoolImport, импорт забирает его, иначе загрузка сохраняется в новый файл.
+func (h *Handler) importUpload(c *gin.Context, body io.Reader, format string, sync bool, opts service.ImportOptions) {
+	if sync {
+		reader, err := importer.NewReader(format, body, c.QueryMap("mapping"))
+		if err != nil {
+			respondProblem(c, uploadErrorStatus(err, http.StatusUnprocessableEntity), err.Error())
+			return
+		}
+		report, err := h.service.Import(c.Request.Context(), reader, opts, auditInfo(c), nil)
+		if err != nil {
+			status := errorStatus(err)
+			h.logger.Errorf("Failed to import people with %d: %v", status, err)
+			writeProblem(c, status, struct {
+				Problem
+				Report *model.ImportReport `json:"report"`
+			}{newProblem(c, status, "failed to import people"), report})
+			return
+		}
+		render(c, http.StatusOK, "report", report)
+		return
+	}
+
+	// Загрузка сохраняется во временный файл: фоновый импорт продолжается после завершения запроса.
+	file, ok := body.(*os.File)
+	if !ok {
+		if file, ok = h.spoolImport(c, body); !ok {
+			return
+		}
+	}
+	cleanup := func() {
+		removeUpload(file)
+	}
+
+	reader, err := importer.NewReader(format, file, c.QueryMap("mapping"))
+	if err != nil {
+		cleanup()
+		respondProblem(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	job, err := h.service.StartImportJob(reader, opts, auditInfo(c), cleanup)
+	if err != nil {
+		cleanup()
+		h.logger.Errorf("Failed to start import job: %v", err)
+		respondProblem(c, http.StatusInternalServerError, "failed to start import")
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+job.ID)
+	render(c, http.StatusAccepted, "job", job)
+}
+
+// spoolImport сохраняет загрузку во временный файл. При ошибке отвечает 413 или 500 и возвращает false.
+func (h *Handler) spoolImport(c *gin.Context, body io.Reader) (*os.File, bool) {
+	file, err := spoolUpload(body)
+	if err != nil {
+		status := uploadErrorStatus(err, http.StatusInternalServerError)
+		if status == http.StatusRequestEntityTooLarge {
+			h.logger.Warnf("Import upload rejected: %v", err)
+			respondProblem(c, status, err.Error())
+			return nil, false
+		}
+		h.logger.Errorf("Failed to store import upload: %v", err)
+		respondProblem(c, status, "failed to store upload")
+		return nil, false
+	}
+	return file, true
+}
+
+// GetImportJob обработчик получения состояния фонового импорта.
+func (h *Handler) GetImportJob(c *gin.Context) {
+	job, err := h.service.GetImportJob(c.Param("job"))
+	if err != nil {
+		h.respondError(c, err, "failed to get import job")
+		return
+	}
+	render(c, http.StatusOK, "job", job)
+}
+
+// importBody возвращает поток загружаемого файла и его формат, пустой, если формат не определен.
+// Из multipart/form-data читается поле file без буферизации остальных частей.
+func importBody(c *gin.Context) (io.Reader, string, error) {
+	format := c.Query("format")
+	mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+
+	if mediaType != "multipart/form-data" {
+		if format == "" {
+			format = importFormats[mediaType]
+		}
+		return c.Request.Body, normalizeFormat(format), nil
+	}
+
+	parts, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return nil, "", errors.New("multipart form has no file field")
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		if format == "" {
+			format = importFormats[part.Header.Get("Content-Type")]
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(part.FileName())), ".")
+		}
+		return part, normalizeFormat(format), nil
+	}
+}
+
+// normalizeFormat возвращает поддерживаемый формат импорта или пустую строку.
+func normalizeFormat(format string) string {
+	switch format = strings.ToLower(format); format {
+	case importer.FormatCSV, importer.FormatNDJSON, importer.FormatXLSX:
+		return format
+	case "jsonl":
+		return importer.FormatNDJSON
+	}
+	return ""
+}
+
+// uploadErrorStatus возвращает 413 для ошибки чтения загрузки больше допустимого размера и status для остальных.
+func uploadErrorStatus(err error, status int) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return status
+}
+
+// spoolUpload копирует загрузку во временный файл и возвращает его открытым с начала.
+func spoolUpload(body io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp("", "people-import-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(file, body); err == nil {
+		if _, err = file.Seek(0, io.SeekStart); err == nil {
+			return file, nil
+		}
+	}
+	removeUpload(file)
+	return nil, err
+}
+
+// removeUpload закрывает и удаляет временный файл загрузки.
+func removeUpload(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestImportPeople(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+
+	upload := "Имя,Фамилия,Возраст\nIvan,Ivanov,30\n,Petrov,40\nAnna,Smirnova,abc\n"
+	target := "/people/import?mapping[Имя]=name&mapping[Фамилия]=surname&mapping[Возраст]=age"
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(upload))
+	req.Header.Set("Content-Type", "text/csv")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var report model.ImportReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Accepted != 1 || report.Rejected != 2 {
+		t.Fatalf("Expected 1 accepted and 2 rejected rows, got %+v", report)
+	}
+	rows := map[int]bool{}
+	for _, rowErr := range report.Errors {
+		rows[rowErr.Row] = true
+	}
+	if !rows[2] || !rows[3] {
+		t.Errorf("Expected errors for rows 2 and 3, got %+v", report.Errors)
+	}
+
+	// Асинхронный импорт возвращает задачу, состояние которой доступно по Location.
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/people/import?format=ndjson&async=true", strings.NewReader(`{"name":"Olga","surname":"Sidorova"}`+"\n"))
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	location := w.Header().Get("Location")
+
+	var job model.ImportJob
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for %s, got %d", location, w.Code)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != model.ImportJobRunning {
+			break
+		}
+	}
+	if job.Status != model.ImportJobCompleted || job.Report.Accepted != 1 {
+		t.Errorf("Expected completed job with 1 accepted row, got %+v", job)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/people/import/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown job, got %d", w.Code)
+	}
+}
+
+func TestImportPeopleLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 64, LegacyAPI{})
+
+	upload := "name,surname\n" + strings.Repeat("Ivan,Ivanov\n", 10)
+	for _, target := range []string{"/people/import", "/people/import?async=true"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(upload))
+		req.Header.Set("Content-Type", "text/csv")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: expected 413, got %d: %s", target, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestImportPeopleIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{})
+
+	send := func(target, key, contentType string, body io.Reader) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, target, body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(IdempotencyKeyHeader, key)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	// multipart отправляет загрузку с новой границей частей, хеш запроса от нее не зависит.
+	sendForm := func(key, upload string) *httptest.ResponseRecorder {
+		var form bytes.Buffer
+		writer := multipart.NewWriter(&form)
+		part, _ := writer.CreateFormFile("file", "people.csv")
+		part.Write([]byte(upload))
+		writer.Close()
+		return send("/people/import", key, writer.FormDataContentType(), &form)
+	}
+
+	upload := "name,surname\nIvan,Ivanov\n"
+	first := sendForm("import-1", upload)
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", first.Code, first.Body.String())
+	}
+	replay := send(APIPrefixV1+"/people/import", "import-1", "text/csv", strings.NewReader(upload))
+	if replay.Code != http.StatusOK || replay.Header().Get("Idempotent-Replayed") != "true" || replay.Body.String() != first.Body.String() {
+		t.Errorf("Expected replay of %q, got %d %q", first.Body.String(), replay.Code, replay.Body.String())
+	}
+	if w := sendForm("import-1", "name,surname\nPetr,Petrov\n"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a different upload, got %d", w.Code)
+	}
+
+	// Повтор фонового импорта возвращает ту же задачу и не запускает новую.
+	job := send("/people/import?async=true", "import-2", "text/csv", strings.NewReader("name,surname\nOlga,Sidorova\n"))
+	again := send("/people/import?async=true", "import-2", "text/csv", strings.NewReader("name,surname\nOlga,Sidorova\n"))
+	if job.Code != http.StatusAccepted || again.Code != http.StatusAccepted || again.Body.String() != job.Body.String() {
+		t.Fatalf("Expected replay of the import job, got %d %q and %d %q", job.Code, job.Body.String(), again.Code, again.Body.String())
+	}
+}
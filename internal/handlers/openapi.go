@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/service"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+)
+
+// routeParam параметр пути маршрута gin, например :id.
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// schemaRules правила проверки model.FieldError по полям схемы OpenAPI, чтобы сообщения совпадали
+// с сообщениями проверки в обработчиках.
+var schemaRules = map[string]string{
+	"required":  "required",
+	"maxLength": "max",
+	"minimum":   "gte",
+	"maximum":   "lte",
+	"enum":      "oneof",
+}
+
+// msgpackBodyHandle разбирает MessagePack в значения, которые можно записать в JSON.
+var msgpackBodyHandle = func() *codec.MsgpackHandle {
+	handle := &codec.MsgpackHandle{}
+	handle.RawToString = true
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return handle
+}()
+
+func init() {
+	// openapi3filter не знает тип тела JSON Merge Patch, остальные типы JSON и YAML в спецификации уже зарегистрированы.
+	openapi3filter.RegisterBodyDecoder(MergePatchContentType, openapi3filter.JSONBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("application/xml", xmlBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("application/msgpack", msgpackBodyDecoder)
+}
+
+// OpenAPIValidation middleware проверяет запросы к маршрутам API по спецификации spec до обработчиков:
+// параметры пути, запроса и заголовков и тела в JSON, XML, YAML и MessagePack. Синонимы типов, например
+// text/xml, проверяются по схеме основного типа. Тела других типов, например файлы импорта,
+// проверяют обработчики, не читая их в память целиком.
+// Некорректные параметры завершают запрос с 400, отсутствие If-Match - с 428, нарушение схемы тела - с 422
+// и списком полей. С validateResponses также проверяются ответы: расхождения со спецификацией
+// логируются как ошибки, ответ клиенту не меняется.
+func OpenAPIValidation(spec *openapi3.T, validateResponses bool) gin.HandlerFunc {
+	logger := logging.GetLogger()
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(c *gin.Context) {
+		route := openAPIRoute(spec, c)
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		pathParams := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			pathParams[param.Key] = param.Value
+		}
+		requestOptions := *options
+		contentType := requestContentType(route.Operation, c.ContentType())
+		requestOptions.ExcludeRequestBody = contentType == "" || !isJSONMediaType(contentType) && bodyBinding(contentType) == nil
+		request := c.Request
+		if contentType != "" && contentType != c.ContentType() {
+			request = c.Request.Clone(c.Request.Context())
+			request.Header.Set("Content-Type", contentType)
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    &requestOptions,
+		}
+		err := openapi3filter.ValidateRequest(c.Request.Context(), input)
+		// Проверка читает тело и подменяет его копией, которую должен получить обработчик.
+		c.Request.Body, c.Request.GetBody = request.Body, request.GetBody
+		if err != nil {
+			respondRequestError(c, logger, err)
+			return
+		}
+
+		if !validateResponses {
+			c.Next()
+			return
+		}
+		recorder := &jsonResponseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		if recorder.Status() == http.StatusNotModified {
+			return
+		}
+		responseOptions := *options
+		responseOptions.IncludeResponseStatus = true
+		var body []byte
+		if recorder.body != nil {
+			body = recorder.body.Bytes()
+		} else {
+			responseOptions.ExcludeResponseBody = true
+		}
+		err = openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 recorder.Status(),
+			Header:                 recorder.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(body)),
+			Options:                &responseOptions,
+		})
+		if err != nil {
+			logger.Errorf("OpenAPI contract violation: %d response to %s %s does not match the specification: %v",
+				recorder.Status(), c.Request.Method, c.FullPath(), err)
+		}
+	}
+}
+
+// openAPIRoute возвращает операцию спецификации для маршрута запроса c или nil, если она не описана.
+// Пути спецификации указаны без префикса версии API.
+func openAPIRoute(spec *openapi3.T, c *gin.Context) *routers.Route {
+	if c.FullPath() == "" {
+		return nil
+	}
+	path := routeParam.ReplaceAllString(unversionedPath(c.FullPath()), "{$1}")
+	pathItem := spec.Paths.Find(path)
+	if pathItem == nil {
+		return nil
+	}
+	operation := pathItem.GetOperation(c.Request.Method)
+	if operation == nil {
+		return nil
+	}
+	return &routers.Route{Spec: spec, Path: path, PathItem: pathItem, Method: c.Request.Method, Operation: operation}
+}
+
+// requestContentType возвращает тип тела, описанный в операции: сам contentType или основной тип его формата
+// из mediaTypes. Для неописанных типов возвращается пустая строка: такие тела не проверяются, их отклоняет обработчик.
+func requestContentType(operation *openapi3.Operation, contentType string) string {
+	if hasRequestContent(operation, contentType) {
+		return contentType
+	}
+	format := mediaTypeFormat(contentType)
+	for _, m := range mediaTypes {
+		if format != "" && m.format == format {
+			if hasRequestContent(operation, m.mediaType) {
+				return m.mediaType
+			}
+			break
+		}
+	}
+	return ""
+}
+
+// hasRequestContent сообщает, описано ли в операции тело типа contentType.
+func hasRequestContent(operation *openapi3.Operation, contentType string) bool {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return false
+	}
+	return operation.RequestBody.Value.Content.Get(contentType) != nil
+}
+
+// msgpackBodyDecoder разбирает тело MessagePack для проверки по схеме через его JSON-представление.
+func msgpackBodyDecoder(body io.Reader, header http.Header, schema *openapi3.SchemaRef, encFn openapi3filter.EncodingFn) (interface{}, error) {
+	var value interface{}
+	if err := codec.NewDecoder(body, msgpackBodyHandle).Decode(&value); err != nil {
+		return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+	}
+	return openapi3filter.JSONBodyDecoder(bytes.NewReader(data), header, schema, encFn)
+}
+
+// xmlBodyDecoder разбирает тело XML для проверки по схеме. Корневой элемент соответствует телу,
+// вложенные элементы - полям объекта или элементам массива, а текст приводится к типу из схемы,
+// как при привязке XML к DTO в обработчиках.
+func xmlBodyDecoder(body io.Reader, _ http.Header, schema *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (interface{}, error) {
+	decoder := xml.NewDecoder(body)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root, err := readXMLElement(decoder, start)
+			if err != nil {
+				return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+			}
+			return root.value(schema), nil
+		}
+	}
+}
+
+// xmlElement элемент XML с текстом и вложенными элементами.
+type xmlElement struct {
+	name     string
+	text     string
+	children []*xmlElement
+}
+
+// readXMLElement читает из decoder содержимое элемента start до его закрывающего тега.
+func readXMLElement(decoder *xml.Decoder, start xml.StartElement) (*xmlElement, error) {
+	element := &xmlElement{name: start.Name.Local}
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := readXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			element.children = append(element.children, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			element.text = text.String()
+			return element, nil
+		}
+	}
+}
+
+// value возвращает значение элемента для проверки по schema: объект, массив, число, логическое значение или строку.
+// Текст, который нельзя привести к типу схемы, остается строкой, чтобы проверка сообщила о неверном типе.
+func (e *xmlElement) value(schema *openapi3.SchemaRef) interface{} {
+	var s *openapi3.Schema
+	if schema != nil {
+		s = schema.Value
+	}
+	switch {
+	case s != nil && s.Type.Is(openapi3.TypeArray):
+		items := make([]interface{}, 0, len(e.children))
+		for _, child := range e.children {
+			items = append(items, child.value(s.Items))
+		}
+		return items
+	case len(e.children) > 0 || s != nil && s.Type.Is(openapi3.TypeObject):
+		object := make(map[string]interface{}, len(e.children))
+		for _, child := range e.children {
+			var property *openapi3.SchemaRef
+			if s != nil {
+				property = s.Properties[child.name]
+			}
+			object[child.name] = child.value(property)
+		}
+		return object
+	case s != nil && (s.Type.Is(openapi3.TypeInteger) || s.Type.Is(openapi3.TypeNumber)):
+		if n, err := strconv.ParseFloat(strings.TrimSpace(e.text), 64); err == nil {
+			return n
+		}
+	case s != nil && s.Type.Is(openapi3.TypeBoolean):
+		if b, err := strconv.ParseBool(strings.TrimSpace(e.text)); err == nil {
+			return b
+		}
+	}
+	return e.text
+}
+
+// isJSONMediaType сообщает, является ли contentType типом JSON, например application/json или application/merge-patch+json.
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// respondRequestError отвечает на запрос, не прошедший проверку по спецификации.
+func respondRequestError(c *gin.Context, logger *logging.Logger, err error) {
+	var fieldErrs model.ValidationErrors
+	for _, requestErr := range requestErrors(err) {
+		switch {
+		case requestErr.Parameter != nil:
+			logger.Warnf("%s %s rejected by OpenAPI validation: %v", c.Request.Method, c.FullPath(), requestErr)
+			parameter := requestErr.Parameter
+			if parameter.In == openapi3.ParameterInHeader && parameter.Name == "If-Match" && errors.Is(requestErr.Err, openapi3filter.ErrInvalidRequired) {
+				respondProblem(c, http.StatusPreconditionRequired, "If-Match header is required")
+				return
+			}
+			if parameter.In == openapi3.ParameterInPath && parameter.Name == "id" {
+				respondProblem(c, http.StatusBadRequest, "invalid person ID")
+				return
+			}
+			respondProblem(c, http.StatusBadRequest, fmt.Sprintf("invalid %s parameter", parameter.Name))
+			return
+		case requestErr.RequestBody != nil:
+			schemaErrs := schemaErrors(requestErr.Err)
+			if len(schemaErrs) == 0 {
+				logger.Warnf("%s %s rejected by OpenAPI validation: %v", c.Request.Method, c.FullPath(), requestErr)
+				respondProblem(c, http.StatusBadRequest, "invalid request payload")
+				return
+			}
+			for _, schemaErr := range schemaErrs {
+				fieldErrs = append(fieldErrs, newSchemaFieldError(schemaErr))
+			}
+		}
+	}
+	if len(fieldErrs) > 0 {
+		writeError(c, logger, fmt.Errorf("%w: %w", service.ErrValidation, fieldErrs), "invalid request payload")
+		return
+	}
+	logger.Warnf("%s %s rejected by OpenAPI validation: %v", c.Request.Method, c.FullPath(), err)
+	respondProblem(c, http.StatusBadRequest, "invalid request")
+}
+
+// requestErrors возвращает ошибки проверки запроса из err, в том числе из openapi3.MultiError.
+func requestErrors(err error) []*openapi3filter.RequestError {
+	switch e := err.(type) {
+	case *openapi3filter.RequestError:
+		return []*openapi3filter.RequestError{e}
+	case openapi3.MultiError:
+		var requestErrs []*openapi3filter.RequestError
+		for _, err := range e {
+			requestErrs = append(requestErrs, requestErrors(err)...)
+		}
+		return requestErrs
+	}
+	return nil
+}
+
+// schemaErrors возвращает нарушения схемы из err, в том числе из openapi3.MultiError.
+func schemaErrors(err error) []*openapi3.SchemaError {
+	switch e := err.(type) {
+	case *openapi3.SchemaError:
+		return []*openapi3.SchemaError{e}
+	case openapi3.MultiError:
+		var schemaErrs []*openapi3.SchemaError
+		for _, err := range e {
+			schemaErrs = append(schemaErrs, schemaErrors(err)...)
+		}
+		return schemaErrs
+	}
+	return nil
+}
+
+// newSchemaFieldError переводит нарушение схемы тела в model.FieldError с правилом проверки обработчиков,
+// если оно есть, иначе с названием поля схемы.
+func newSchemaFieldError(schemaErr *openapi3.SchemaError) model.FieldError {
+	field := strings.Join(schemaErr.JSONPointer(), ".")
+	if field == "" {
+		field = "body"
+	}
+	fieldErr := model.FieldError{Field: field, Rule: schemaErr.SchemaField}
+	if rule, ok := schemaRules[schemaErr.SchemaField]; ok {
+		fieldErr.Rule = rule
+	}
+	if schema := schemaErr.Schema; schema != nil {
+		switch schemaErr.SchemaField {
+		case "maxLength":
+			if schema.MaxLength != nil {
+				fieldErr.Param = fmt.Sprint(*schema.MaxLength)
+			}
+		case "minimum":
+			if schema.Min != nil {
+				fieldErr.Param = fmt.Sprint(*schema.Min)
+			}
+		case "maximum":
+			if schema.Max != nil {
+				fieldErr.Param = fmt.Sprint(*schema.Max)
+			}
+		case "enum":
+			values := make([]string, 0, len(schema.Enum))
+			for _, value := range schema.Enum {
+				if value != "" {
+					values = append(values, fmt.Sprint(value))
+				}
+			}
+			fieldErr.Param = strings.Join(values, " ")
+		}
+	}
+	fieldErr.Message = fieldErr.Localize(model.LanguageEnglish)
+	return fieldErr
+}
+
+// jsonResponseRecorder дублирует в буфер тело JSON-ответа для проверки по спецификации.
+// Тела других типов, например файлы выгрузки, не сохраняются.
+type jsonResponseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *jsonResponseRecorder) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *jsonResponseRecorder) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *jsonResponseRecorder) record(data []byte) {
+	if w.body == nil {
+		if !isJSONMediaType(w.Header().Get("Content-Type")) {
+			return
+		}
+		w.body = &bytes.Buffer{}
+	}
+	w.body.Write(data)
+}
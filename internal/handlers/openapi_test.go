@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/api"
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestOpenAPIValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	spec, err := api.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	logger := logging.GetLogger()
+	hook := test.NewLocal(logger.Logger)
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	router.Use(AdminAuth("secret"))
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{}, OpenAPIValidation(spec, true))
+
+	if err := repo.CreatePerson(context.Background(), &model.Person{Name: "Ivan", Surname: "Ivanov"}, model.AuditInfo{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	serve := func(method, target, contentType, body string, headers ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("RejectsRequests", func(t *testing.T) {
+		for _, tt := range []struct {
+			method, target, body string
+			status               int
+			detail               string
+		}{
+			{http.MethodGet, "/api/v1/people?age=old", "", http.StatusBadRequest, "invalid age parameter"},
+			{http.MethodGet, "/people?consistency=never", "", http.StatusBadRequest, "invalid consistency parameter"},
+			{http.MethodGet, "/api/v1/people/abc", "", http.StatusBadRequest, "invalid person ID"},
+			{http.MethodPut, "/api/v1/people/1", `{"name":"Petr","surname":"Petrov"}`, http.StatusPreconditionRequired, "If-Match header is required"},
+			{http.MethodPost, "/api/v1/people", `{"name":`, http.StatusBadRequest, "invalid request payload"},
+		} {
+			w := serve(tt.method, tt.target, "application/json", tt.body)
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || w.Code != tt.status || problem.Detail != tt.detail {
+				t.Errorf("%s %s: expected %d %q, got %d %s", tt.method, tt.target, tt.status, tt.detail, w.Code, w.Body.String())
+			}
+		}
+
+		w := serve(http.MethodPost, "/api/v1/people", "application/json", `{"name":"Ivan","patronymic":"`+strings.Repeat("a", 256)+`"}`)
+		var problem Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Unexpected response %d %s", w.Code, w.Body.String())
+		}
+		expected := []model.FieldError{
+			{Field: "patronymic", Rule: "max", Param: "255", Message: "patronymic must be at most 255 characters"},
+			{Field: "surname", Rule: "required", Message: "surname is required"},
+		}
+		if !reflect.DeepEqual(problem.Errors, expected) {
+			t.Errorf("Unexpected field errors %+v", problem.Errors)
+		}
+	})
+
+	t.Run("ResponsesMatchSpecification", func(t *testing.T) {
+		hook.Reset()
+		for _, tt := range []struct {
+			method, target, contentType, body string
+			status                            int
+		}{
+			{http.MethodGet, "/api/v1/people?sort=-age&include_deleted=true", "", "", http.StatusOK},
+			{http.MethodGet, "/api/v1/people/1", "", "", http.StatusOK},
+			{http.MethodGet, "/api/v1/people/42", "", "", http.StatusNotFound},
+			{http.MethodPatch, "/api/v1/people/1", MergePatchContentType, `{"age":31}`, http.StatusOK},
+			{http.MethodGet, "/api/v1/people/1/history", "", "", http.StatusOK},
+			{http.MethodDelete, "/api/v1/people/1", "", "", http.StatusOK},
+			{http.MethodPost, "/api/v1/people/1/restore", "", "", http.StatusOK},
+			{http.MethodPost, "/api/v1/people/import", "text/csv", "name,surname\nAnna,Petrova\n,Empty\n", http.StatusOK},
+			{http.MethodGet, "/api/v1/people/import/unknown", "", "", http.StatusNotFound},
+			{http.MethodGet, "/api/v1/people/export?format=ndjson", "", "", http.StatusOK},
+		} {
+			w := serve(tt.method, tt.target, tt.contentType, tt.body, "If-Match", "*", "Authorization", "Bearer secret")
+			if w.Code != tt.status {
+				t.Errorf("%s %s: expected %d, got %d %s", tt.method, tt.target, tt.status, w.Code, w.Body.String())
+			}
+		}
+		for _, entry := range hook.AllEntries() {
+			if entry.Level == logrus.ErrorLevel && strings.HasPrefix(entry.Message, "OpenAPI contract violation") {
+				t.Error(entry.Message)
+			}
+		}
+	})
+}
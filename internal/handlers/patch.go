@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"testProject/internal/model"
+	"testProject/service"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// MergePatchContentType тип тела JSON Merge Patch (RFC 7396).
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType тип тела JSON Patch (RFC 6902).
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+// errInvalidPatch патч не удалось применить или результат не прошел валидацию.
+var errInvalidPatch = fmt.Errorf("%w: invalid patch", service.ErrValidation)
+
+// PatchPerson обработчик частичного обновления информации о человеке.
+// Принимает application/merge-patch+json или application/json-patch+json
+// и изменяет в базе данных только затронутые патчем поля.
+// Требует заголовок If-Match с текущим ETag записи и возвращает 412, если запись уже изменили.
+func (h *Handler) PatchPerson(c *gin.Context) {
+	h.logger.Debug("Handling PatchPerson request")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("Failed to parse person ID: %v", err)
+		respondProblem(c, http.StatusBadRequest, "invalid person ID")
+		return
+	}
+
+	version, ok := h.ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		h.logger.Errorf("Failed to read request body: %v", err)
+		respondProblem(c, http.StatusBadRequest, "invalid request payload")
+		return
+	}
+
+	var apply func(document []byte) ([]byte, error)
+	switch c.ContentType() {
+	case MergePatchContentType:
+		if !json.Valid(body) {
+			respondProblem(c, http.StatusBadRequest, "invalid merge patch document")
+			return
+		}
+		apply = func(document []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(document, body)
+		}
+	case JSONPatchContentType:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			h.logger.Errorf("Failed to decode JSON patch: %v", err)
+			respondProblem(c, http.StatusBadRequest, "invalid JSON patch document")
+			return
+		}
+		apply = patch.Apply
+	default:
+		c.Header("Accept-Patch", MergePatchContentType+", "+JSONPatchContentType)
+		respondProblem(c, http.StatusUnsupportedMediaType, "unsupported patch content type")
+		return
+	}
+
+	person, err := h.service.PatchPerson(c.Request.Context(), id, version, func(person *model.Person) error {
+		return applyPatch(person, apply)
+	}, auditInfo(c))
+	if err != nil {
+		h.respondError(c, err, "failed to patch person")
+		return
+	}
+
+	c.Header("ETag", formatETag(person.Version))
+	render(c, http.StatusOK, "person", newPersonResponse(person))
+}
+
+// applyPatch применяет apply к JSON-представлению UpdatePersonRequest из person и валидирует результат.
+// Патч видит только изменяемые клиентом поля, поля сервера в документе отсутствуют и патчем не меняются.
+// Поля, удаленные патчем, получают нулевые значения.
+func applyPatch(person *model.Person, apply func(document []byte) ([]byte, error)) error {
+	document, err := json.Marshal(newUpdatePersonRequest(person))
+	if err != nil {
+		return err
+	}
+
+	patched, err := apply(document)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+
+	var request UpdatePersonRequest
+	if err := json.Unmarshal(patched, &request); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+	result := *person
+	request.apply(&result)
+	if err := result.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidPatch, err)
+	}
+
+	*person = result
+	return nil
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"testProject/internal/model"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+func TestApplyPatch(t *testing.T) {
+	original := model.Person{ID: 7, Name: "Ivan", Surname: "Ivanov", Patronymic: "Ivanovich", Age: 30}
+
+	person := original
+	err := applyPatch(&person, func(document []byte) ([]byte, error) {
+		return jsonpatch.MergePatch(document, []byte(`{"surname":"Petrov","patronymic":null}`))
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if person.ID != 7 || person.Name != "Ivan" || person.Surname != "Petrov" || person.Patronymic != "" || person.Age != 30 {
+		t.Errorf("Unexpected merge patch result: %+v", person)
+	}
+
+	patch, err := jsonpatch.DecodePatch([]byte(`[{"op":"replace","path":"/age","value":31},{"op":"test","path":"/name","value":"Ivan"}]`))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	person = original
+	if err := applyPatch(&person, patch.Apply); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if person.Age != 31 || person.Surname != "Ivanov" {
+		t.Errorf("Unexpected JSON patch result: %+v", person)
+	}
+
+	failing, _ := jsonpatch.DecodePatch([]byte(`[{"op":"test","path":"/name","value":"Petr"}]`))
+	person = original
+	if err := applyPatch(&person, failing.Apply); !errors.Is(err, errInvalidPatch) {
+		t.Errorf("Expected errInvalidPatch, but got %v", err)
+	}
+	if person != original {
+		t.Errorf("Expected person to be unchanged after failed patch, got %+v", person)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"testProject/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType тип тела ответа с ошибкой (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// Problem описание ошибки по RFC 7807.
+// Type определяется статусом ответа, например /problems/not-found, RequestID совпадает с заголовком X-Request-ID,
+// Errors перечисляет нарушенные правила проверки полей для ошибок валидации.
+type Problem struct {
+	Type      string             `json:"type"`
+	Title     string             `json:"title"`
+	Status    int                `json:"status"`
+	Detail    string             `json:"detail,omitempty"`
+	Instance  string             `json:"instance,omitempty"`
+	RequestID string             `json:"request_id,omitempty"`
+	Errors    []model.FieldError `json:"errors,omitempty"`
+}
+
+// newProblem возвращает описание ошибки запроса c со статусом status.
+func newProblem(c *gin.Context, status int, detail string) Problem {
+	return Problem{
+		Type:      problemType(status),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		RequestID: c.GetString(requestIDKey),
+	}
+}
+
+// problemType возвращает тип ошибки по статусу: "/problems/" и текст статуса через дефис.
+func problemType(status int) string {
+	text := strings.ToLower(strings.ReplaceAll(http.StatusText(status), "'", ""))
+	if text == "" {
+		return "about:blank"
+	}
+	return "/problems/" + strings.ReplaceAll(text, " ", "-")
+}
+
+// respondProblem отвечает на запрос ошибкой application/problem+json и прерывает обработку запроса.
+func respondProblem(c *gin.Context, status int, detail string) {
+	writeProblem(c, status, newProblem(c, status, detail))
+}
+
+// writeProblem записывает body, Problem или структуру с ним и дополнительными полями, как ответ application/problem+json.
+func writeProblem(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(status, body)
+}
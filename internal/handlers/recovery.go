@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"testProject/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery переводит панику обработчика в ответ 500 application/problem+json и логирует ее со стеком.
+// Разрыв соединения клиентом gin обрабатывает сам, ответ в этом случае не пишется.
+func Recovery(logger *logging.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered interface{}) {
+		logger.Errorf("%s %s panicked: %v\n%s", c.Request.Method, c.Request.URL.Path, recovered, debug.Stack())
+		respondProblem(c, http.StatusInternalServerError, "internal error")
+	})
+}
@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"testProject/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+	"gopkg.in/yaml.v3"
+)
+
+// Форматы тел запросов и ответов API людей.
+const (
+	FormatJSON    = "json"
+	FormatXML     = "xml"
+	FormatYAML    = "yaml"
+	FormatMsgPack = "msgpack"
+	FormatCSV     = "csv"
+)
+
+// mediaTypes типы содержимого и их форматы в порядке предпочтения сервера: при Accept */* ответ будет в JSON.
+// Для каждого формата первым указан основной тип, остальные - распространенные синонимы.
+var mediaTypes = []struct {
+	mediaType string
+	format    string
+}{
+	{"application/json", FormatJSON},
+	{"application/xml", FormatXML},
+	{"text/xml", FormatXML},
+	{"application/yaml", FormatYAML},
+	{"application/x-yaml", FormatYAML},
+	{"text/yaml", FormatYAML},
+	{"application/msgpack", FormatMsgPack},
+	{"application/x-msgpack", FormatMsgPack},
+	{"text/csv", FormatCSV},
+}
+
+// msgpackHandle кодирует строки типом str спецификации MessagePack 2.0, а не устаревшим raw.
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+// responseMediaTypeKey ключ контекста с типом содержимого ответа, выбранным Negotiation.
+const responseMediaTypeKey = "response_media_type"
+
+// xmlItemNames имена элементов XML для элементов массивов по имени массива, для остальных - item.
+var xmlItemNames = map[string]string{
+	"people":  "person",
+	"changes": "change",
+	"errors":  "error",
+}
+
+// Negotiation middleware выбирает тип содержимого ответа по заголовку Accept до обработчика, чтобы
+// изменяющий запрос не выполнялся, если ответ на него нельзя отправить. Поддерживаются JSON, XML, YAML,
+// MessagePack, а с lists - CSV для списков. Неподдерживаемый Accept завершает запрос с 406.
+// Ошибки всегда возвращаются в application/problem+json.
+func Negotiation(lists bool) gin.HandlerFunc {
+	var offered []string
+	for _, mediaType := range mediaTypes {
+		if mediaType.format != FormatCSV || lists {
+			offered = append(offered, mediaType.mediaType)
+		}
+	}
+
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept")
+		mediaType := negotiateMediaType(c.GetHeader("Accept"), offered)
+		if mediaType == "" {
+			respondProblem(c, http.StatusNotAcceptable, "unsupported Accept media type, use "+strings.Join(offered, ", "))
+			return
+		}
+		c.Set(responseMediaTypeKey, mediaType)
+		c.Next()
+	}
+}
+
+// negotiateMediaType возвращает тип из offered, наиболее подходящий под заголовок Accept с учетом весов q,
+// или пустую строку, если подходящего нет. Без заголовка возвращается первый тип.
+func negotiateMediaType(accept string, offered []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, r := range ranges {
+		for _, mediaType := range offered {
+			prefix, wildcard := strings.CutSuffix(r.mediaType, "/*")
+			if r.mediaType == mediaType || wildcard && (prefix == "*" || strings.HasPrefix(mediaType, prefix+"/")) {
+				return mediaType
+			}
+		}
+	}
+	return ""
+}
+
+// mediaTypeFormat возвращает формат типа содержимого или пустую строку, если тип не поддерживается.
+func mediaTypeFormat(mediaType string) string {
+	for _, m := range mediaTypes {
+		if m.mediaType == mediaType {
+			return m.format
+		}
+	}
+	return ""
+}
+
+// render отвечает телом body в формате, выбранном Negotiation, по умолчанию в JSON.
+// Тело сначала кодируется в JSON, а остальные форматы получаются из него, поэтому имена полей
+// и значения во всех форматах совпадают с JSON и спецификацией OpenAPI. name задает корневой элемент XML.
+func render(c *gin.Context, status int, name string, body interface{}) {
+	mediaType := c.GetString(responseMediaTypeKey)
+	format := mediaTypeFormat(mediaType)
+	if format == "" || format == FormatJSON {
+		c.JSON(status, body)
+		return
+	}
+
+	data, err := encodeBody(format, name, body)
+	if err != nil {
+		writeError(c, logging.GetLogger(), fmt.Errorf("failed to encode %s response: %w", format, err), "failed to encode response")
+		return
+	}
+	if format != FormatMsgPack {
+		mediaType += "; charset=utf-8"
+	}
+	c.Data(status, mediaType, data)
+}
+
+// encodeBody кодирует body в формат format через его JSON-представление.
+func encodeBody(format, name string, body interface{}) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeJSONValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatXML:
+		buf.WriteString(xml.Header)
+		encoder := xml.NewEncoder(&buf)
+		if err := writeXMLElement(encoder, name, value); err != nil {
+			return nil, err
+		}
+		err = encoder.Flush()
+	case FormatYAML:
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(yamlNode(value)); err != nil {
+			return nil, err
+		}
+		err = encoder.Close()
+	case FormatMsgPack:
+		err = codec.NewEncoder(&buf, msgpackHandle).Encode(plainValue(value))
+	case FormatCSV:
+		err = writeCSV(&buf, value, csvColumns(reflect.TypeOf(body)))
+	default:
+		err = fmt.Errorf("unsupported format %q", format)
+	}
+	return buf.Bytes(), err
+}
+
+// jsonObject объект JSON с полями в исходном порядке.
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+// decodeJSONValue читает из decoder значение JSON: jsonObject, []interface{}, string, json.Number, bool или nil.
+func decodeJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		object := jsonObject{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, jsonField{key: key.(string), value: value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	case '[':
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	}
+	return nil, fmt.Errorf("unexpected JSON delimiter %q", delim)
+}
+
+// writeXMLElement записывает value элементом name: поля объекта - вложенными элементами, элементы массива -
+// элементами с именем из xmlItemNames. Поля со значением null пропускаются.
+func writeXMLElement(encoder *xml.Encoder, name string, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case jsonObject:
+		for _, field := range v {
+			if err := writeXMLElement(encoder, field.key, field.value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		itemName, ok := xmlItemNames[name]
+		if !ok {
+			itemName = "item"
+		}
+		for _, item := range v {
+			if err := writeXMLElement(encoder, itemName, item); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := encoder.EncodeToken(xml.CharData(scalarString(v))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// yamlNode возвращает узел YAML для значения JSON с тем же порядком полей.
+func yamlNode(value interface{}) *yaml.Node {
+	switch v := value.(type) {
+	case jsonObject:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		if len(v) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		for _, field := range v {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field.key}, yamlNode(field.value))
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		if len(v) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scalarString(value)}
+}
+
+// plainValue возвращает значение JSON в виде map[string]interface{}, []interface{}, int64, float64, string, bool или nil.
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case jsonObject:
+		object := make(map[string]interface{}, len(v))
+		for _, field := range v {
+			object[field.key] = plainValue(field.value)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, item := range v {
+			array[i] = plainValue(item)
+		}
+		return array
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		n, _ := v.Float64()
+		return n
+	}
+	return value
+}
+
+// writeCSV записывает массив объектов таблицей: колонки - columns, затем поля объектов, которых нет в columns,
+// в порядке первого появления. Вложенные объекты и массивы записываются ячейками с JSON, null - пустыми ячейками.
+// Заголовок записывается и для пустого массива, если columns не пусты.
+func writeCSV(buf *bytes.Buffer, value interface{}, columns []string) error {
+	rows, ok := value.([]interface{})
+	if !ok {
+		return errors.New("CSV requires a list")
+	}
+
+	columns = append([]string(nil), columns...)
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[column] = i
+	}
+	for _, row := range rows {
+		object, ok := row.(jsonObject)
+		if !ok {
+			return errors.New("CSV requires a list of objects")
+		}
+		for _, field := range object {
+			if _, ok := index[field.key]; !ok {
+				index[field.key] = len(columns)
+				columns = append(columns, field.key)
+			}
+		}
+	}
+
+	writer := csv.NewWriter(buf)
+	if len(columns) > 0 {
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for _, field := range row.(jsonObject) {
+			switch field.value.(type) {
+			case jsonObject, []interface{}:
+				cell, err := json.Marshal(plainValue(field.value))
+				if err != nil {
+					return err
+				}
+				record[index[field.key]] = string(cell)
+			case nil:
+			default:
+				record[index[field.key]] = scalarString(field.value)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvColumns возвращает колонки CSV по полям JSON элемента списка типа t, если элемент - структура,
+// чтобы заголовок таблицы не зависел от данных. Поля встроенных структур разворачиваются, как в encoding/json.
+func csvColumns(t reflect.Type) []string {
+	if t == nil || (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) {
+		return nil
+	}
+	return structColumns(t.Elem())
+}
+
+func structColumns(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			columns = append(columns, structColumns(field.Type)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, name)
+	}
+	return columns
+}
+
+// scalarString возвращает текст строки, числа или логического значения JSON.
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// bodyBinding возвращает привязку gin для типа содержимого тела запроса или nil, если тип не поддерживается.
+// Тело без Content-Type разбирается как JSON.
+func bodyBinding(mediaType string) binding.BindingBody {
+	switch mediaTypeFormat(mediaType) {
+	case FormatJSON:
+		return binding.JSON
+	case FormatXML:
+		return binding.XML
+	case FormatYAML:
+		return binding.YAML
+	case FormatMsgPack:
+		return binding.MsgPack
+	}
+	if mediaType == "" {
+		return binding.JSON
+	}
+	return nil
+}
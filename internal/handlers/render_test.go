@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/api"
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/ugorji/go/codec"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	offered := []string{"application/json", "application/xml", "text/xml", "text/csv"}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"text/*", "text/xml"},
+		{"text/html, application/xml;q=0.9, */*;q=0.1", "application/xml"},
+		{"application/json;q=0.5, text/csv", "text/csv"},
+		{"application/xml;q=0, text/xml", "text/xml"},
+		{"application/yaml", ""},
+		{"text/html, image/*", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateMediaType(tt.accept, offered); got != tt.want {
+			t.Errorf("Accept %q: expected %q, got %q", tt.accept, tt.want, got)
+		}
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	spec, err := api.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	logger := logging.GetLogger()
+	hook := test.NewLocal(logger.Logger)
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, LegacyAPI{}, OpenAPIValidation(spec, true))
+
+	for _, person := range []model.Person{{Name: "Ivan", Surname: "Ivanov", Age: 30}, {Name: "Anna", Surname: "Ivanova", Age: 25}} {
+		if err := repo.CreatePerson(context.Background(), &person, model.AuditInfo{}); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+	}
+
+	serve := func(method, target, accept, contentType string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, bytes.NewReader(body))
+		req.Header.Set("Accept", accept)
+		req.Header.Set("If-Match", "*")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Responses", func(t *testing.T) {
+		w := serve(http.MethodGet, "/api/v1/people/1", "application/xml", "", nil)
+		var person struct {
+			XMLName xml.Name `xml:"person"`
+			ID      uint     `xml:"id"`
+			Name    string   `xml:"name"`
+			Age     int      `xml:"age"`
+		}
+		if err := xml.Unmarshal(w.Body.Bytes(), &person); err != nil || w.Code != http.StatusOK || person.Name != "Ivan" || person.Age != 30 {
+			t.Errorf("Unexpected XML response %d %s", w.Code, w.Body.String())
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/xml; charset=utf-8" {
+			t.Errorf("Unexpected Content-Type %q", contentType)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("Unexpected Vary %q", vary)
+		}
+
+		w = serve(http.MethodGet, "/api/v1/people?sort=name", "application/x-yaml", "", nil)
+		var yamlPeople []map[string]interface{}
+		if err := yaml.Unmarshal(w.Body.Bytes(), &yamlPeople); err != nil || len(yamlPeople) != 2 || yamlPeople[0]["name"] != "Anna" || yamlPeople[0]["age"] != 25 {
+			t.Errorf("Unexpected YAML response %d %s", w.Code, w.Body.String())
+		}
+
+		w = serve(http.MethodGet, "/api/v1/people?sort=name", "application/msgpack", "", nil)
+		var people []map[string]interface{}
+		handle := &codec.MsgpackHandle{}
+		handle.RawToString = true
+		if err := codec.NewDecoderBytes(w.Body.Bytes(), handle).Decode(&people); err != nil || len(people) != 2 || people[1]["name"] != "Ivan" {
+			t.Errorf("Unexpected MessagePack response %d %v %v", w.Code, people, err)
+		}
+
+		w = serve(http.MethodGet, "/api/v1/people?sort=name", "text/csv", "", nil)
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil || len(records) != 3 || strings.Join(records[0][:5], ",") != "id,name,surname,patronymic,age" || records[1][1] != "Anna" {
+			t.Errorf("Unexpected CSV response %d %v %v", w.Code, records, err)
+		}
+
+		// Колонки берутся из полей ответа, поэтому заголовок есть и у пустого списка.
+		w = serve(http.MethodGet, "/api/v1/people?offset=10", "text/csv", "", nil)
+		records, err = csv.NewReader(w.Body).ReadAll()
+		if err != nil || len(records) != 1 || strings.Join(records[0], ",") != "id,name,surname,patronymic,age,gender,nationality,version,created_at,updated_at,deleted_at,enriched_at" {
+			t.Errorf("Unexpected CSV response for an empty list %d %v %v", w.Code, records, err)
+		}
+	})
+
+	t.Run("NotAcceptable", func(t *testing.T) {
+		for _, target := range []string{"/api/v1/people/1", "/api/v1/people?limit=1"} {
+			w := serve(http.MethodGet, target, "text/html", "", nil)
+			if w.Code != http.StatusNotAcceptable || w.Header().Get("Content-Type") != ProblemContentType {
+				t.Errorf("%s: unexpected response %d %s", target, w.Code, w.Body.String())
+			}
+		}
+		// CSV доступен только для списков.
+		if w := serve(http.MethodGet, "/api/v1/people/1", "text/csv", "", nil); w.Code != http.StatusNotAcceptable {
+			t.Errorf("Unexpected response %d %s", w.Code, w.Body.String())
+		}
+		// Запрос с неподдерживаемым Accept не выполняется.
+		w := serve(http.MethodPut, "/api/v1/people/2", "text/html", "application/json", []byte(`{"name":"Olga","surname":"Ivanova"}`))
+		if person, _ := repo.GetPersonById(context.Background(), 2); w.Code != http.StatusNotAcceptable || person.Name != "Anna" {
+			t.Errorf("Unexpected response %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Requests", func(t *testing.T) {
+		var msgpack []byte
+		if err := codec.NewEncoderBytes(&msgpack, &codec.MsgpackHandle{}).Encode(map[string]interface{}{"name": "Oleg", "surname": "Olegov", "age": 41}); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		for _, tt := range []struct {
+			contentType string
+			body        []byte
+			name        string
+			age         int
+		}{
+			{"application/xml", []byte(`<person><name>Petr</name><surname>Petrov</surname><age>40</age></person>`), "Petr", 40},
+			{"application/yaml", []byte("name: Olga\nsurname: Olgina\nage: 35\n"), "Olga", 35},
+			{"application/msgpack", msgpack, "Oleg", 41},
+		} {
+			w := serve(http.MethodPut, "/api/v1/people/1", "application/json", tt.contentType, tt.body)
+			person, err := repo.GetPersonById(context.Background(), 1)
+			if w.Code != http.StatusOK || err != nil || person.Name != tt.name || person.Age != tt.age {
+				t.Errorf("%s: unexpected response %d %s", tt.contentType, w.Code, w.Body.String())
+			}
+		}
+
+		w := serve(http.MethodPut, "/api/v1/people/1", "application/json", "application/xml", []byte(`<person><name>Petr</name></person>`))
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Unexpected response %d %s", w.Code, w.Body.String())
+		}
+
+		// Тела всех форматов проверяются по спецификации до обработчика: неверный тип поля - 422, а не ошибка привязки.
+		var invalidMsgpack []byte
+		if err := codec.NewEncoderBytes(&invalidMsgpack, &codec.MsgpackHandle{}).Encode(map[string]interface{}{"name": "Oleg", "surname": "Olegov", "age": "old"}); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		for _, tt := range []struct {
+			contentType string
+			body        []byte
+		}{
+			{"application/xml", []byte(`<person><name>Petr</name><surname>Petrov</surname><age>old</age></person>`)},
+			{"text/xml", []byte(`<person><name>Petr</name><surname>Petrov</surname><age>old</age></person>`)},
+			{"application/yaml", []byte("name: Olga\nsurname: Olgina\nage: old\n")},
+			{"text/yaml", []byte("name: Olga\nsurname: Olgina\nage: old\n")},
+			{"application/msgpack", invalidMsgpack},
+		} {
+			w := serve(http.MethodPut, "/api/v1/people/1", "application/json", tt.contentType, tt.body)
+			var problem struct {
+				Errors []model.FieldError `json:"errors"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || w.Code != http.StatusUnprocessableEntity ||
+				len(problem.Errors) != 1 || problem.Errors[0].Field != "age" || problem.Errors[0].Rule != "type" {
+				t.Errorf("%s: unexpected response %d %s", tt.contentType, w.Code, w.Body.String())
+			}
+		}
+		w = serve(http.MethodPut, "/api/v1/people/1", "application/json", "text/xml", []byte(`<person><name>Petr</name><surname>Petrov</surname><age>40</age></person>`))
+		if w.Code != http.StatusOK {
+			t.Errorf("Unexpected response %d %s", w.Code, w.Body.String())
+		}
+		w = serve(http.MethodPost, "/api/v1/people", "application/json", "text/plain", []byte("Ivan Ivanov"))
+		if w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("Unexpected response %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.ErrorLevel {
+			t.Errorf("Unexpected error log: %s", entry.Message)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"testProject/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader заголовок с идентификатором запроса.
+	RequestIDHeader = "X-Request-ID"
+	// ActorHeader заголовок с именем автора изменений для истории изменений.
+	// Значение не проверяется и задается клиентом, поэтому автор в истории может быть подделан.
+	ActorHeader = "X-Actor"
+
+	requestIDKey      = "request_id"
+	anonymousActor    = "anonymous"
+	maxHeaderIDLength = 255
+)
+
+// RequestID middleware, которое присваивает запросу идентификатор из заголовка X-Request-ID
+// или генерирует новый и возвращает его в ответе.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxHeaderIDLength {
+			requestID = newRequestID()
+		}
+		c.Set(requestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// newRequestID генерирует случайный идентификатор запроса.
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// auditInfo возвращает автора и идентификатор запроса для истории изменений.
+// Автор берется из заголовка X-Actor как есть, без аутентификации.
+func auditInfo(c *gin.Context) model.AuditInfo {
+	actor := c.GetHeader(ActorHeader)
+	if actor == "" || len(actor) > maxHeaderIDLength {
+		actor = anonymousActor
+	}
+	return model.AuditInfo{Actor: actor, RequestID: c.GetString(requestIDKey)}
+}
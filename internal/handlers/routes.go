@@ -1,20 +1,69 @@
 package handlers
 
 import (
+	"net/http"
 	"testProject/pkg/logging"
 	"testProject/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// APIPrefixV1 префикс маршрутов первой версии API.
+const APIPrefixV1 = "/api/v1"
+
 // RegisterRoutes регистрирует маршруты HTTP для взаимодействия с обработчиками, используемыми сервисом.
-func RegisterRoutes(router *gin.Engine, service *service.Service) {
-	handler := NewHandler(*service, logging.GetLogger())
+// Маршруты версии 1 доступны с префиксом APIPrefixV1 и по прежним путям без префикса,
+// ответы по прежним путям содержат заголовки устаревания со сроками из legacy.
+// Изменяющие запросы с заголовком Idempotency-Key обрабатываются через idempotency с временем жизни idempotencyTTL,
+// незавершенный запрос удерживает ключ дольше самого долгого времени обработки маршрута.
+// Время обработки запроса ограничено requestTimeout или значением из routeTimeouts для маршрута.
+// Загрузки импорта больше maxImportSize байт отклоняются с 413.
+// middleware выполняются для маршрутов версии 1 перед обработчиками, например OpenAPIValidation.
+// Общие RequestID, Recovery, Timeout и Consistency подключаются ко всему router и действуют и для маршрутов,
+// зарегистрированных после RegisterRoutes, например RegisterGraphQLRoutes.
+// Неизвестный путь, метод, не поддерживаемый путем, и паника обработчика возвращают application/problem+json
+// со статусами 404, 405 и 500.
+func RegisterRoutes(router *gin.Engine, service *service.Service, idempotency IdempotencyStore, idempotencyTTL time.Duration,
+	requestTimeout time.Duration, routeTimeouts map[string]time.Duration, maxImportSize int64, legacy LegacyAPI, middleware ...gin.HandlerFunc) {
+	logger := logging.GetLogger()
+	handler := NewHandler(*service, logger)
+	handler.maxImportSize = maxImportSize
+	handler.idempotency = newIdempotencyGuard(idempotency, idempotencyTTL, idempotencyLease(requestTimeout, routeTimeouts), logger)
+
+	router.Use(RequestID(), Recovery(logger), Timeout(requestTimeout, routeTimeouts), Consistency())
+	router.NoRoute(func(c *gin.Context) {
+		respondProblem(c, http.StatusNotFound, "route not found")
+	})
+	router.HandleMethodNotAllowed = true
+	router.NoMethod(func(c *gin.Context) {
+		respondProblem(c, http.StatusMethodNotAllowed, "method not allowed")
+	})
+
+	idempotent := handler.idempotency.middleware
+	registerV1(router.Group(APIPrefixV1, middleware...), handler, idempotent)
+	registerV1(router.Group("", append([]gin.HandlerFunc{Deprecation(legacy, APIPrefixV1)}, middleware...)...), handler, idempotent)
+}
 
-	router.POST("/people", handler.CreatePerson)
-	router.GET("/people", handler.GetPeople)
-	router.GET("/people/:id", handler.GetPersonById)
-	router.PUT("/people/:id", handler.UpdatePerson)
-	router.DELETE("/people/:id", handler.DeletePerson)
+// registerV1 регистрирует в group маршруты версии 1.
+// Следующая версия со своими DTO регистрируется отдельной функцией под своим префиксом,
+// сервис и middleware у версий общие, поэтому версии могут работать одновременно.
+func registerV1(group *gin.RouterGroup, handler *Handler, idempotency gin.HandlerFunc) {
+	people := group.Group("/people")
+	// Формат ответа выбирается до Idempotency, чтобы ответ 406 не сохранялся для повтора запроса.
+	// Выгрузка отдает файл в формате из параметра format и от Accept не зависит.
+	// Импорт читает загрузку потоком и сам проверяет ключ идемпотентности по хешу загрузки, не буферизуя тело.
+	record, list := Negotiation(false), Negotiation(true)
 
+	people.POST("", record, idempotency, handler.CreatePerson)
+	people.GET("", list, idempotency, handler.GetPeople)
+	people.GET("/export", idempotency, handler.ExportPeople)
+	people.GET("/:id", record, idempotency, handler.GetPersonById)
+	people.GET("/:id/history", list, idempotency, handler.GetPersonHistory)
+	people.PUT("/:id", record, idempotency, handler.UpdatePerson)
+	people.PATCH("/:id", record, idempotency, handler.PatchPerson)
+	people.DELETE("/:id", record, idempotency, handler.DeletePerson)
+	people.POST("/:id/restore", record, idempotency, handler.RestorePerson)
+	people.POST("/import", record, handler.ImportPeople)
+	people.GET("/import/:job", record, idempotency, handler.GetImportJob)
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"testProject/pkg/logging"
+	"testProject/repository"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logging.GetLogger()
+	repo := repository.NewMemoryRepository(logger)
+	router := gin.New()
+	legacy := LegacyAPI{
+		DeprecatedAt: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC),
+	}
+	RegisterRoutes(router, service.NewService(repo, logger), repo, time.Hour, time.Minute, nil, 1<<20, legacy)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	w := serve("/api/v1/people")
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" || w.Header().Get("Sunset") != "" {
+		t.Errorf("Unexpected versioned response %d %v", w.Code, w.Header())
+	}
+
+	w = serve("/people?limit=5")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected legacy response %d %q", w.Code, w.Body.String())
+	}
+	for header, expected := range map[string]string{
+		"Deprecation": "@1792368000",
+		"Sunset":      "Fri, 30 Apr 2027 00:00:00 GMT",
+		"Link":        `</api/v1/people>; rel="successor-version"`,
+	} {
+		if value := w.Header().Get(header); value != expected {
+			t.Errorf("Expected %s %q, got %q", header, expected, value)
+		}
+	}
+
+	if w := serve("/api/v2/people"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown API version, got %d", w.Code)
+	}
+}
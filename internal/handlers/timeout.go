@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout middleware ограничивает время обработки запроса: по истечении timeout контекст запроса отменяется,
+// и вместе с ним прерываются запросы к базе данных и внешним сервисам.
+// routes задает время для отдельных маршрутов в виде "METHOD /path", например "POST /people".
+// Путь без префикса версии API относится ко всем версиям, путь с префиксом - только к своей версии.
+func Timeout(timeout time.Duration, routes map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := timeout
+		if routeTimeout, ok := routes[c.Request.Method+" "+c.FullPath()]; ok {
+			d = routeTimeout
+		} else if routeTimeout, ok := routes[c.Request.Method+" "+unversionedPath(c.FullPath())]; ok {
+			d = routeTimeout
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// unversionedPath возвращает path без префикса версии API вида /api/v1.
+func unversionedPath(path string) string {
+	rest, ok := strings.CutPrefix(path, "/api/v")
+	if !ok {
+		return path
+	}
+	if i := strings.IndexByte(rest, '/'); i > 0 && strings.Trim(rest[:i], "0123456789") == "" {
+		return rest[i:]
+	}
+	return path
+}
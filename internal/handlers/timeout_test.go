@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Timeout(time.Second, map[string]time.Duration{"GET /slow/:id": time.Hour}))
+	remaining := func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if !ok {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, time.Until(deadline).Round(time.Second).String())
+	}
+	router.GET("/fast", remaining)
+	router.GET("/slow/:id", remaining)
+	router.GET("/api/v1/slow/:id", remaining)
+
+	for path, expected := range map[string]string{"/fast": "1s", "/slow/1": "1h0m0s", "/api/v1/slow/1": "1h0m0s"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != expected {
+			t.Errorf("Expected deadline in %s for %s, got %d %q", expected, path, w.Code, w.Body.String())
+		}
+	}
+}
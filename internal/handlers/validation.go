@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"testProject/internal/model"
+	"testProject/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"golang.org/x/text/language"
+)
+
+// languages языки сообщений об ошибках проверки, первый используется по умолчанию.
+var languages = language.NewMatcher([]language.Tag{language.English, language.Russian})
+
+// validationDetails описание ошибки проверки данных по языку.
+var validationDetails = map[string]string{
+	model.LanguageEnglish: "request validation failed",
+	model.LanguageRussian: "данные запроса не прошли проверку",
+}
+
+// Тела запросов проверяются теми же правилами, что и записи в сервисе. Валидатор gin общий для процесса,
+// поэтому он заменяется один раз при загрузке пакета, а не при каждой регистрации маршрутов.
+func init() {
+	binding.Validator = structValidator{}
+}
+
+// structValidator проверяет тела запросов, которые разбирает gin, правилами model.ValidateStruct.
+type structValidator struct{}
+
+func (structValidator) ValidateStruct(obj interface{}) error {
+	return model.ValidateStruct(obj)
+}
+
+func (structValidator) Engine() interface{} {
+	return model.Validator()
+}
+
+// requestLanguage возвращает язык сообщений об ошибках, наиболее подходящий под заголовок Accept-Language.
+func requestLanguage(c *gin.Context) string {
+	tags, _, _ := language.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	tag, _, _ := languages.Match(tags...)
+	base, _ := tag.Base()
+	return base.String()
+}
+
+// bindBody разбирает тело запроса в DTO request по заголовку Content-Type: JSON, XML, YAML или MessagePack.
+// Неподдерживаемый тип завершает запрос с 415, некорректное тело - с 400.
+func (h *Handler) bindBody(c *gin.Context, request interface{}) bool {
+	bind := bodyBinding(c.ContentType())
+	if bind == nil {
+		respondProblem(c, http.StatusUnsupportedMediaType, "unsupported request content type, use application/json, application/xml, application/yaml or application/msgpack")
+		return false
+	}
+	if err := c.ShouldBindWith(request, bind); err != nil {
+		h.logger.Errorf("Failed to bind request body: %v", err)
+		respondProblem(c, http.StatusBadRequest, "invalid request payload")
+		return false
+	}
+	return true
+}
+
+// validatePerson проверяет person правилами model.Person. Нарушение правил завершает запрос с 422 и списком полей.
+func (h *Handler) validatePerson(c *gin.Context, person *model.Person) bool {
+	if err := person.Validate(); err != nil {
+		h.respondError(c, fmt.Errorf("%w: %w", service.ErrValidation, err), "invalid request payload")
+		return false
+	}
+	return true
+}
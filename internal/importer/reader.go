@@ -0,0 +1,236 @@
+// Package importer читает людей из файлов массового импорта.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"testProject/internal/model"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrInvalidRow строку не удалось разобрать. Ошибки с ней относятся к одной строке, чтение можно продолжать.
+var ErrInvalidRow = errors.New("invalid row")
+
+// Форматы файлов импорта.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// SkipColumn значение в сопоставлении колонок, при котором колонка игнорируется.
+const SkipColumn = "-"
+
+// maxLineSize наибольшая длина строки NDJSON.
+const maxLineSize = 1 << 20
+
+// Reader читает людей из файла импорта по одному, в конце возвращает io.EOF.
+type Reader interface {
+	Read() (model.Person, error)
+}
+
+// NewReader возвращает Reader для формата format.
+// mapping сопоставляет названия колонок (ключей NDJSON) полям model.Person, колонки без сопоставления
+// должны называться как поля. CSV и NDJSON читаются потоком, XLSX - архив, поэтому файл читается в память целиком.
+func NewReader(format string, r io.Reader, mapping map[string]string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.ReuseRecord = true
+		return newTableReader(reader.Read, true, mapping)
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		return &ndjsonReader{scanner: scanner, mapping: mapping}, nil
+	case FormatXLSX:
+		return newXLSXReader(r, mapping)
+	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
+
+// tableReader читает таблицу, первая строка которой содержит названия колонок.
+type tableReader struct {
+	next    func() ([]string, error)
+	strict  bool
+	columns []string
+}
+
+// newTableReader читает заголовок таблицы из next. Если strict, строки должны содержать столько же ячеек, сколько заголовок.
+func newTableReader(next func() ([]string, error), strict bool, mapping map[string]string) (*tableReader, error) {
+	header, err := next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make([]string, len(header))
+	for i, column := range header {
+		if columns[i], err = mapField(column, mapping); err != nil {
+			return nil, err
+		}
+	}
+	return &tableReader{next: next, strict: strict, columns: columns}, nil
+}
+
+func (r *tableReader) Read() (model.Person, error) {
+	record, err := r.next()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return model.Person{}, fmt.Errorf("%w: %v", ErrInvalidRow, err)
+		}
+		return model.Person{}, err
+	}
+	if len(record) > len(r.columns) || (r.strict && len(record) != len(r.columns)) {
+		return model.Person{}, fmt.Errorf("%w: expected %d fields, got %d", ErrInvalidRow, len(r.columns), len(record))
+	}
+
+	var person model.Person
+	for i, value := range record {
+		if err := setPersonField(&person, r.columns[i], strings.TrimSpace(value)); err != nil {
+			return model.Person{}, fmt.Errorf("%w: %v", ErrInvalidRow, err)
+		}
+	}
+	return person, nil
+}
+
+// newXLSXReader читает первый лист книги XLSX.
+func newXLSXReader(r io.Reader, mapping map[string]string) (Reader, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX: %w", err)
+	}
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("XLSX has no sheets")
+	}
+	rows, err := file.Rows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XLSX: %w", err)
+	}
+
+	return newTableReader(func() ([]string, error) {
+		for rows.Next() {
+			columns, err := rows.Columns()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidRow, err)
+			}
+			// Пустые строки листа пропускаются, как и в NDJSON.
+			if len(columns) > 0 {
+				return columns, nil
+			}
+		}
+		if err := rows.Error(); err != nil {
+			return nil, err
+		}
+		rows.Close()
+		return nil, io.EOF
+	}, false, mapping)
+}
+
+// ndjsonReader читает по одному JSON-объекту человека на строку, пустые строки пропускаются.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	mapping map[string]string
+}
+
+func (r *ndjsonReader) Read() (model.Person, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		person, err := r.parse(line)
+		if err != nil {
+			return model.Person{}, fmt.Errorf("%w: %v", ErrInvalidRow, err)
+		}
+		return person, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return model.Person{}, err
+	}
+	return model.Person{}, io.EOF
+}
+
+func (r *ndjsonReader) parse(line []byte) (model.Person, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(line, &object); err != nil {
+		return model.Person{}, err
+	}
+
+	var person model.Person
+	for key, raw := range object {
+		field, err := mapField(key, r.mapping)
+		if err != nil {
+			return model.Person{}, err
+		}
+		value := string(raw)
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			value = text
+		} else if value == "null" {
+			value = ""
+		}
+		if err := setPersonField(&person, field, strings.TrimSpace(value)); err != nil {
+			return model.Person{}, err
+		}
+	}
+	return person, nil
+}
+
+// mapField возвращает поле model.Person для колонки column с учетом mapping или SkipColumn.
+func mapField(column string, mapping map[string]string) (string, error) {
+	field, ok := mapping[column]
+	if !ok {
+		field = strings.ToLower(strings.TrimSpace(column))
+	}
+	if field == SkipColumn || isPersonField(field) {
+		return field, nil
+	}
+	return "", fmt.Errorf("unknown column %q", column)
+}
+
+// isPersonField сообщает, можно ли импортировать поле model.Person с названием name.
+func isPersonField(name string) bool {
+	for _, field := range model.PersonFilterFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setPersonField записывает текстовое значение value в поле field.
+func setPersonField(person *model.Person, field, value string) error {
+	switch field {
+	case "name":
+		person.Name = value
+	case "surname":
+		person.Surname = value
+	case "patronymic":
+		person.Patronymic = value
+	case "age":
+		if value == "" {
+			return nil
+		}
+		age, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid age %q", value)
+		}
+		person.Age = age
+	case "gender":
+		person.Gender = value
+	case "nationality":
+		person.Nationality = value
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"testProject/internal/model"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func readAll(t *testing.T, reader Reader) ([]model.Person, int) {
+	var people []model.Person
+	invalid := 0
+	for {
+		person, err := reader.Read()
+		if err == io.EOF {
+			return people, invalid
+		}
+		if errors.Is(err, ErrInvalidRow) {
+			invalid++
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		people = append(people, person)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "Name,surname,age\nIvan,Ivanov,30\nAnna,Petrova,unknown\nJohn,Smith\nPetr,Petrov,\n"
+	reader, err := NewReader(FormatCSV, strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	people, invalid := readAll(t, reader)
+	if invalid != 2 || len(people) != 2 {
+		t.Fatalf("Expected 2 valid and 2 invalid rows, got %+v and %d", people, invalid)
+	}
+	if people[0] != (model.Person{Name: "Ivan", Surname: "Ivanov", Age: 30}) || people[1].Name != "Petr" {
+		t.Errorf("Unexpected people: %+v", people)
+	}
+
+	if _, err := NewReader(FormatCSV, strings.NewReader("name,id\n"), nil); err == nil {
+		t.Error("Expected error for unknown column")
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"name":"Ivan","surname":"Ivanov","age":30}
+
+{"name":"Anna","id":5}
+not json
+{"name":"John","surname":"Smith"}`
+	reader, err := NewReader(FormatNDJSON, strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	people, invalid := readAll(t, reader)
+	if invalid != 2 || len(people) != 2 || people[0].Age != 30 || people[1].Name != "John" {
+		t.Errorf("Unexpected people %+v and %d invalid rows", people, invalid)
+	}
+}
+
+func TestReaderMapping(t *testing.T) {
+	mapping := map[string]string{"Имя": "name", "Фамилия": "surname", "Комментарий": SkipColumn}
+
+	reader, err := NewReader(FormatCSV, strings.NewReader("Имя,Фамилия,Комментарий\nIvan,Ivanov,vip\n"), mapping)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	people, invalid := readAll(t, reader)
+	if invalid != 0 || len(people) != 1 || people[0] != (model.Person{Name: "Ivan", Surname: "Ivanov"}) {
+		t.Errorf("Unexpected people %+v and %d invalid rows", people, invalid)
+	}
+
+	reader, err = NewReader(FormatNDJSON, strings.NewReader(`{"Имя":"Anna","Фамилия":"Petrova","age":25,"gender":null}`), mapping)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	people, invalid = readAll(t, reader)
+	if invalid != 0 || len(people) != 1 || people[0] != (model.Person{Name: "Anna", Surname: "Petrova", Age: 25}) {
+		t.Errorf("Unexpected people %+v and %d invalid rows", people, invalid)
+	}
+}
+
+func TestXLSXReader(t *testing.T) {
+	file := excelize.NewFile()
+	sheet := file.GetSheetName(0)
+	for i, row := range [][]interface{}{
+		{"name", "surname", "age"},
+		{"Ivan", "Ivanov", 30},
+		{"Anna", "Petrova"},
+		{"John", "Smith", "old"},
+	} {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := file.SetSheetRow(sheet, cell, &row); err != nil {
+			t.Fatalf("Failed to fill XLSX: %v", err)
+		}
+	}
+	var buffer bytes.Buffer
+	if err := file.Write(&buffer); err != nil {
+		t.Fatalf("Failed to write XLSX: %v", err)
+	}
+
+	reader, err := NewReader(FormatXLSX, &buffer, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	people, invalid := readAll(t, reader)
+	if invalid != 1 || len(people) != 2 || people[0].Age != 30 || people[1].Surname != "Petrova" {
+		t.Errorf("Unexpected people %+v and %d invalid rows", people, invalid)
+	}
+}
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// BulkRowError ошибка в строке массового создания, Row - индекс строки во входных данных.
+type BulkRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// BulkCreateResult результат массового создания людей.
+// IDs содержит идентификаторы созданных записей в порядке входных строк, у отклоненных строк 0.
+type BulkCreateResult struct {
+	IDs    []uint         `json:"ids"`
+	Errors []BulkRowError `json:"errors"`
+}
+
+// Created возвращает количество созданных записей.
+func (r *BulkCreateResult) Created() int {
+	return len(r.IDs) - len(r.Errors)
+}
+
+// MaxImportErrors наибольшее количество причин отклонения строк в отчете об импорте.
+const MaxImportErrors = 1000
+
+// ImportReport отчет об импорте: количество принятых и отклоненных строк и причины отклонения.
+// Row в Errors - номер строки данных начиная с единицы, хранятся первые MaxImportErrors причин.
+type ImportReport struct {
+	Accepted int            `json:"accepted"`
+	Rejected int            `json:"rejected"`
+	Errors   []BulkRowError `json:"errors"`
+}
+
+// Reject учитывает отклоненную строку row с причиной reason.
+func (r *ImportReport) Reject(row int, reason string) {
+	r.Rejected++
+	if len(r.Errors) < MaxImportErrors {
+		r.Errors = append(r.Errors, BulkRowError{Row: row, Error: reason})
+	}
+}
+
+// Статусы фонового импорта.
+const (
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+// ImportJob фоновый импорт. Report обновляется по мере загрузки строк,
+// Error заполнен, если импорт прерван ошибкой.
+type ImportJob struct {
+	ID         string       `json:"id"`
+	Status     string       `json:"status"`
+	Report     ImportReport `json:"report"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
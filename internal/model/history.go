@@ -0,0 +1,70 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Операции над записью о человеке, которые попадают в историю изменений.
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationPurge   = "purge"
+)
+
+// AuditInfo сведения о том, кто и в рамках какого запроса изменяет данные.
+type AuditInfo struct {
+	Actor     string
+	RequestID string
+}
+
+// PersonChange запись истории изменений человека.
+// Before и After содержат состояние записи до и после изменения, Diff - только изменившиеся поля.
+type PersonChange struct {
+	ID        int64        `db:"id" json:"id"`
+	PersonID  uint         `db:"person_id" json:"person_id"`
+	Operation string       `db:"operation" json:"operation"`
+	Actor     string       `db:"actor" json:"actor"`
+	RequestID string       `db:"request_id" json:"request_id"`
+	ChangedAt time.Time    `db:"changed_at" json:"changed_at"`
+	Before    JSONDocument `db:"before" json:"before"`
+	After     JSONDocument `db:"after" json:"after"`
+	Diff      JSONDocument `db:"diff" json:"diff"`
+}
+
+// JSONDocument JSON-документ, хранимый в колонке JSONB. Пустой документ соответствует NULL.
+type JSONDocument []byte
+
+// Scan реализует sql.Scanner.
+func (d *JSONDocument) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = nil
+	case []byte:
+		*d = append(JSONDocument(nil), v...)
+	case string:
+		*d = JSONDocument(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONDocument", src)
+	}
+	return nil
+}
+
+// Value реализует driver.Valuer.
+func (d JSONDocument) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return nil, nil
+	}
+	return string(d), nil
+}
+
+// MarshalJSON возвращает документ без изменений или null для пустого документа.
+func (d JSONDocument) MarshalJSON() ([]byte, error) {
+	if len(d) == 0 {
+		return []byte("null"), nil
+	}
+	return d, nil
+}
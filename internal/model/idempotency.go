@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// IdempotencyRecord сохраненный ответ на запрос с заголовком Idempotency-Key.
+// StatusCode равный 0 означает, что запрос с этим ключом еще обрабатывается: до LockedUntil
+// ключ принадлежит этому запросу, после - повтору, если обработавший запрос процесс не сохранил ответ.
+type IdempotencyRecord struct {
+	Key         string     `db:"key"`
+	RequestHash string     `db:"request_hash"`
+	StatusCode  int        `db:"status_code"`
+	ContentType string     `db:"content_type"`
+	Body        []byte     `db:"body"`
+	CreatedAt   time.Time  `db:"created_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	LockedUntil *time.Time `db:"locked_until"`
+}
@@ -1,11 +1,94 @@
 package model
 
+import (
+	"strconv"
+	"time"
+)
+
+// Person информация о человеке.
+// Version увеличивается при каждом изменении записи и используется для оптимистичной блокировки,
+// нулевое значение Version при изменении означает, что версия не проверяется.
+// DeletedAt заполнен у удаленных записей, которые еще можно восстановить.
+// CreatedAt, UpdatedAt и EnrichedAt проставляет сервер, в API и историю изменений они попадают только через DTO ответа.
+// Теги binding задают правила проверки, длины строк совпадают с размерами колонок таблицы people.
 type Person struct {
-	ID          uint   `db:"id" json:"-"`
-	Name        string `db:"name" json:"name"`
-	Surname     string `db:"surname" json:"surname"`
-	Patronymic  string `db:"patronymic" json:"patronymic"`
-	Age         int    `db:"age" json:"age"`
-	Gender      string `db:"gender" json:"gender"`
-	Nationality string `db:"nationality" json:"nationality"`
+	ID          uint       `db:"id" json:"-"`
+	Name        string     `db:"name" json:"name" binding:"required,max=255,person_name"`
+	Surname     string     `db:"surname" json:"surname" binding:"required,max=255,person_name"`
+	Patronymic  string     `db:"patronymic" json:"patronymic" binding:"omitempty,max=255,person_name"`
+	Age         int        `db:"age" json:"age" binding:"person_age"`
+	Gender      string     `db:"gender" json:"gender" binding:"omitempty,max=10,oneof=male female"`
+	Nationality string     `db:"nationality" json:"nationality" binding:"omitempty,max=50,iso3166_1_alpha2"`
+	Version     int        `db:"version" json:"-"`
+	DeletedAt   *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"-"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"-"`
+	EnrichedAt  *time.Time `db:"enriched_at" json:"-"`
+}
+
+// MaxAge наибольший допустимый возраст человека, из него строится правило person_age.
+const MaxAge = 150
+
+// Validate проверяет поля человека правилами из тегов binding и возвращает ValidationErrors со всеми нарушениями.
+func (p *Person) Validate() error {
+	return ValidateStruct(p)
+}
+
+// ValidateInput проверяет поля, которые клиент задает при создании человека: имя, фамилию и отчество.
+func (p *Person) ValidateInput() error {
+	return ValidateStructPartial(p, "Name", "Surname", "Patronymic")
+}
+
+// ValidateEnrichment проверяет поля, которые заполняет обогащение: возраст, пол и национальность.
+func (p *Person) ValidateEnrichment() error {
+	return ValidateStructPartial(p, "Age", "Gender", "Nationality")
+}
+
+// PersonFilterFields поля, по которым можно фильтровать список людей.
+var PersonFilterFields = []string{"name", "surname", "patronymic", "age", "gender", "nationality"}
+
+// PersonFieldValues значения фильтров по полям из PersonFilterFields, nil означает, что фильтр по полю не задан.
+type PersonFieldValues struct {
+	Name        *string
+	Surname     *string
+	Patronymic  *string
+	Age         *int32
+	Gender      *string
+	Nationality *string
+}
+
+// Fields возвращает заданные значения в виде PersonFilter.Fields.
+func (v PersonFieldValues) Fields() map[string]string {
+	fields := make(map[string]string)
+	for field, value := range map[string]*string{
+		"name": v.Name, "surname": v.Surname, "patronymic": v.Patronymic, "gender": v.Gender, "nationality": v.Nationality,
+	} {
+		if value != nil {
+			fields[field] = *value
+		}
+	}
+	if v.Age != nil {
+		fields["age"] = strconv.Itoa(int(*v.Age))
+	}
+	return fields
+}
+
+// PersonSortFields поля, по которым можно сортировать список людей.
+var PersonSortFields = []string{"id", "name", "surname", "patronymic", "age", "gender", "nationality"}
+
+// SortField поле сортировки списка людей, Desc задает сортировку по убыванию.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// PersonFilter параметры выборки списка людей.
+// Fields содержит значения для сравнения на равенство по полям из PersonFilterFields,
+// Sort - поля из PersonSortFields в порядке приоритета. Без сортировки люди упорядочены по id.
+type PersonFilter struct {
+	Fields         map[string]string
+	Sort           []SortField
+	IncludeDeleted bool
+	Offset         int
+	Limit          int
 }
@@ -0,0 +1,164 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationTag тег структуры с правилами проверки. Совпадает с тегом gin, поэтому те же правила
+// применяются при разборе тела запроса.
+const ValidationTag = "binding"
+
+// Языки сообщений об ошибках проверки.
+const (
+	LanguageEnglish = "en"
+	LanguageRussian = "ru"
+)
+
+// personNamePattern имя, фамилия или отчество: буквы Unicode, между которыми допускаются
+// одиночные пробел, дефис или апостроф, например "Анна-Мария" или "O'Brien".
+var personNamePattern = regexp.MustCompile(`^\p{L}+(?:[ '’-]\p{L}+)*$`)
+
+var validate = newValidator()
+
+// newValidator создает валидатор с тегом ValidationTag, названиями полей из тегов json, правилом person_name
+// и псевдонимом person_age для возраста от 0 до MaxAge.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName(ValidationTag)
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	if err := v.RegisterValidation("person_name", func(fl validator.FieldLevel) bool {
+		return personNamePattern.MatchString(fl.Field().String())
+	}); err != nil {
+		panic(err)
+	}
+	v.RegisterAlias("person_age", fmt.Sprintf("gte=0,lte=%d", MaxAge))
+	return v
+}
+
+// Validator возвращает валидатор, которым ValidateStruct проверяет структуры.
+func Validator() *validator.Validate {
+	return validate
+}
+
+// ValidateStruct проверяет структуру или указатель на нее правилами из тегов ValidationTag.
+// Возвращает ValidationErrors со всеми нарушениями, значения других типов не проверяются.
+func ValidateStruct(obj interface{}) error {
+	if !isStruct(obj) {
+		return nil
+	}
+	return validationErrors(validate.Struct(obj))
+}
+
+// ValidateStructPartial проверяет только поля fields структуры obj, поля указываются по именам в Go.
+func ValidateStructPartial(obj interface{}, fields ...string) error {
+	if !isStruct(obj) {
+		return nil
+	}
+	return validationErrors(validate.StructPartial(obj, fields...))
+}
+
+// isStruct сообщает, является ли obj структурой или ненулевым указателем на нее.
+func isStruct(obj interface{}) bool {
+	value := reflect.ValueOf(obj)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return false
+		}
+		value = value.Elem()
+	}
+	return value.Kind() == reflect.Struct
+}
+
+// validationErrors переводит ошибки валидатора в ValidationErrors с сообщениями на английском.
+// Для псевдонимов правил, например person_age, в Rule попадает нарушенное правило, в которое раскрывается псевдоним.
+func validationErrors(err error) error {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+	errs := make(ValidationErrors, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		errs[i] = FieldError{Field: fieldErr.Field(), Rule: fieldErr.ActualTag(), Param: fieldErr.Param()}
+		errs[i].Message = errs[i].Localize(LanguageEnglish)
+	}
+	return errs
+}
+
+// FieldError нарушение правила проверки поля, например required или max. Param - параметр правила.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// fieldMessages шаблоны сообщений об ошибках проверки по языку и правилу:
+// {field} заменяется названием поля, {param} - параметром правила.
+var fieldMessages = map[string]map[string]string{
+	LanguageEnglish: {
+		"required":         "{field} is required",
+		"max":              "{field} must be at most {param} characters",
+		"gte":              "{field} must be at least {param}",
+		"lte":              "{field} must be at most {param}",
+		"oneof":            "{field} must be one of: {param}",
+		"iso3166_1_alpha2": "{field} must be an ISO 3166-1 alpha-2 country code",
+		"person_name":      "{field} must contain only letters separated by single spaces, hyphens or apostrophes",
+		"":                 "{field} is invalid",
+	},
+	LanguageRussian: {
+		"required":         "поле {field} обязательно",
+		"max":              "поле {field} должно содержать не более {param} символов",
+		"gte":              "поле {field} должно быть не меньше {param}",
+		"lte":              "поле {field} должно быть не больше {param}",
+		"oneof":            "поле {field} должно иметь одно из значений: {param}",
+		"iso3166_1_alpha2": "поле {field} должно содержать код страны ISO 3166-1 alpha-2",
+		"person_name":      "поле {field} должно содержать только буквы, разделенные одиночными пробелами, дефисами или апострофами",
+		"":                 "поле {field} заполнено некорректно",
+	},
+}
+
+// Localize возвращает сообщение об ошибке на языке lang, для неизвестного языка - на английском.
+func (e FieldError) Localize(lang string) string {
+	messages, ok := fieldMessages[lang]
+	if !ok {
+		messages = fieldMessages[LanguageEnglish]
+	}
+	message, ok := messages[e.Rule]
+	if !ok {
+		message = messages[""]
+	}
+	return strings.NewReplacer("{field}", e.Field, "{param}", e.Param).Replace(message)
+}
+
+// ValidationErrors все нарушения правил проверки записи.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Localize возвращает копию ошибок с сообщениями на языке lang.
+func (e ValidationErrors) Localize(lang string) ValidationErrors {
+	localized := make(ValidationErrors, len(e))
+	for i, fieldErr := range e {
+		localized[i] = fieldErr
+		localized[i].Message = fieldErr.Localize(lang)
+	}
+	return localized
+}
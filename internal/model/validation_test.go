@@ -0,0 +1,65 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPersonValidate(t *testing.T) {
+	valid := Person{Name: "Анна-Мария", Surname: "O'Brien", Patronymic: "Ивановна", Age: 30, Gender: "female", Nationality: "RU"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	for _, test := range []struct {
+		person Person
+		field  string
+		rule   string
+	}{
+		{Person{Surname: "Ivanov"}, "name", "required"},
+		{Person{Name: "  ", Surname: "Ivanov"}, "name", "person_name"},
+		{Person{Name: "Ivan2", Surname: "Ivanov"}, "name", "person_name"},
+		{Person{Name: "Ivan", Surname: strings.Repeat("я", 256)}, "surname", "max"},
+		{Person{Name: "Ivan", Surname: "Ivanov", Age: -1}, "age", "gte"},
+		{Person{Name: "Ivan", Surname: "Ivanov", Age: MaxAge + 1}, "age", "lte"},
+		{Person{Name: "Ivan", Surname: "Ivanov", Gender: "other"}, "gender", "oneof"},
+		{Person{Name: "Ivan", Surname: "Ivanov", Nationality: "XX"}, "nationality", "iso3166_1_alpha2"},
+	} {
+		var errs ValidationErrors
+		if err := test.person.Validate(); !errors.As(err, &errs) || len(errs) != 1 {
+			t.Errorf("Expected one field error for %+v, got %v", test.person, err)
+			continue
+		}
+		if errs[0].Field != test.field || errs[0].Rule != test.rule {
+			t.Errorf("Expected %s %s, got %+v", test.field, test.rule, errs[0])
+		}
+	}
+}
+
+func TestPersonValidatePartial(t *testing.T) {
+	// До обогащения проверяются только поля клиента, данные обогащения - после него.
+	person := Person{Name: "Ivan", Surname: "Ivanov", Age: -1, Nationality: "XX"}
+	if err := person.ValidateInput(); err != nil {
+		t.Errorf("Expected no error, but got %v", err)
+	}
+	var errs ValidationErrors
+	if err := person.ValidateEnrichment(); !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("Expected two field errors, got %v", err)
+	}
+
+	person = Person{Name: "Ivan2", Age: -1}
+	if err := person.ValidateInput(); !errors.As(err, &errs) || len(errs) != 2 || errs[0].Field != "name" || errs[1].Field != "surname" {
+		t.Errorf("Expected name and surname errors, got %v", err)
+	}
+}
+
+func TestFieldErrorLocalize(t *testing.T) {
+	fieldErr := FieldError{Field: "surname", Rule: "max", Param: "255"}
+	if message := fieldErr.Localize(LanguageRussian); message != "поле surname должно содержать не более 255 символов" {
+		t.Errorf("Unexpected russian message %q", message)
+	}
+	if message := fieldErr.Localize("de"); message != "surname must be at most 255 characters" {
+		t.Errorf("Unexpected fallback message %q", message)
+	}
+}
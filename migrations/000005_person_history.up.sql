@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS person_history (
+    id BIGSERIAL PRIMARY KEY,
+    person_id INT NOT NULL,
+    operation VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    before JSONB,
+    after JSONB,
+    diff JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS person_history_person_id_changed_at_idx ON person_history (person_id, changed_at);
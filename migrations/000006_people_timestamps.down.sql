@@ -0,0 +1,5 @@
+ALTER TABLE people DROP COLUMN IF EXISTS enriched_at;
+
+ALTER TABLE people DROP COLUMN IF EXISTS updated_at;
+
+ALTER TABLE people DROP COLUMN IF EXISTS created_at;
\ No newline at end of file
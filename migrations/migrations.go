@@ -0,0 +1,14 @@
+// Package migrations содержит SQL-миграции схемы базы данных.
+package migrations
+
+import "embed"
+
+// Postgres миграции схемы для Postgres, применяются командой migrate или при db.auto_migrate.
+//
+//go:embed *.sql
+var Postgres embed.FS
+
+// SQLite миграции схемы для SQLite, применяются при открытии базы данных.
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
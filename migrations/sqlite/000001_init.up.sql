@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS people (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    surname VARCHAR(255) NOT NULL,
+    patronymic VARCHAR(255),
+    age INT,
+    gender VARCHAR(10),
+    nationality VARCHAR(50),
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS people_deleted_at_idx ON people (deleted_at);
@@ -0,0 +1,5 @@
+ALTER TABLE people DROP COLUMN enriched_at;
+
+ALTER TABLE people DROP COLUMN updated_at;
+
+ALTER TABLE people DROP COLUMN created_at;
\ No newline at end of file
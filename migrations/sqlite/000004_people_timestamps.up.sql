@@ -0,0 +1,7 @@
+ALTER TABLE people ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+ALTER TABLE people ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+ALTER TABLE people ADD COLUMN enriched_at TIMESTAMP;
+
+UPDATE people SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"testProject/internal/model"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// newBulkCreateResult проверяет строки массового создания и возвращает результат с ошибками
+// некорректных строк и индексы строк, которые можно создавать.
+func newBulkCreateResult(people []model.Person) (*model.BulkCreateResult, []int) {
+	result := &model.BulkCreateResult{IDs: make([]uint, len(people)), Errors: []model.BulkRowError{}}
+	valid := make([]int, 0, len(people))
+	for i := range people {
+		if err := people[i].Validate(); err != nil {
+			result.Errors = append(result.Errors, model.BulkRowError{Row: i, Error: err.Error()})
+			continue
+		}
+		valid = append(valid, i)
+	}
+	return result, valid
+}
+
+// bulkCreateByRow создает корректные строки по одной в одной транзакции repo.
+// Используется хранилищами, у которых нет массовой загрузки.
+func bulkCreateByRow(ctx context.Context, repo Storage, people []model.Person, audit model.AuditInfo) (*model.BulkCreateResult, error) {
+	result, valid := newBulkCreateResult(people)
+	if len(valid) == 0 {
+		return result, nil
+	}
+
+	err := repo.WithTx(ctx, func(tx Storage) error {
+		for _, i := range valid {
+			person := people[i]
+			if err := tx.CreatePerson(ctx, &person, audit); err != nil {
+				return err
+			}
+			result.IDs[i] = person.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BulkCreatePeople создает корректные строки people одной транзакцией и записывает их создание в историю.
+// Некорректные строки и строки, которые отклонила база данных, например нарушением ограничения,
+// не создаются и возвращаются в ошибках результата.
+// В Postgres строки загружаются через COPY во временную таблицу и переносятся в people одним запросом,
+// если база данных отклонила строку, пачка создается заново по одной строке. В SQLite строки создаются по одной.
+func (r *Repository) BulkCreatePeople(ctx context.Context, people []model.Person, audit model.AuditInfo) (*model.BulkCreateResult, error) {
+	r.logger.Debugf("Repository: Handling BulkCreatePeople request with %d rows", len(people))
+
+	var result *model.BulkCreateResult
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		var valid []int
+		result, valid = newBulkCreateResult(people)
+		if len(valid) == 0 {
+			return nil
+		}
+		if r.driver != driverPostgres {
+			return r.createRows(ctx, tx, people, valid, audit, result)
+		}
+
+		err := savepoint(ctx, tx, func() error {
+			return r.copyRows(ctx, tx, people, valid, audit, result)
+		})
+		if isRowError(err) {
+			r.logger.Warnf("Bulk insert of %d rows failed, creating them one by one: %v", len(valid), err)
+			return r.createRows(ctx, tx, people, valid, audit, result)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// copyRows загружает строки valid через COPY во временную таблицу и переносит их в people одним запросом.
+// Ошибка любой строки прерывает загрузку всех строк.
+func (r *Repository) copyRows(ctx context.Context, tx *sqlx.Tx, people []model.Person, valid []int, audit model.AuditInfo, result *model.BulkCreateResult) error {
+	// Идентификаторы выделяются заранее, чтобы сопоставить их входным строкам и записать историю.
+	var ids []uint
+	query := "SELECT nextval(pg_get_serial_sequence('people', 'id')) FROM generate_series(1, $1)"
+	if err := sqlx.SelectContext(ctx, tx, &ids, query, len(valid)); err != nil {
+		return err
+	}
+
+	// Временная таблица удаляется при завершении транзакции.
+	staging := `
+        CREATE TEMP TABLE people_import (
+            id INT NOT NULL,
+            name VARCHAR(255) NOT NULL,
+            surname VARCHAR(255) NOT NULL,
+            patronymic VARCHAR(255),
+            age INT,
+            gender VARCHAR(10),
+            nationality VARCHAR(50),
+            enriched_at TIMESTAMPTZ
+        ) ON COMMIT DROP
+    `
+	if _, err := tx.ExecContext(ctx, staging); err != nil {
+		return err
+	}
+
+	err := copyIn(ctx, tx, pq.CopyIn("people_import", "id", "name", "surname", "patronymic", "age", "gender", "nationality", "enriched_at"), len(valid), func(n int) []interface{} {
+		person := people[valid[n]]
+		return []interface{}{ids[n], person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nationality, person.EnrichedAt}
+	})
+	if err != nil {
+		return err
+	}
+
+	merge := `
+        INSERT INTO people (id, name, surname, patronymic, age, gender, nationality, enriched_at)
+        SELECT id, name, surname, patronymic, age, gender, nationality, enriched_at FROM people_import
+    `
+	if _, err := tx.ExecContext(ctx, merge); err != nil {
+		return err
+	}
+
+	changes := make([]model.PersonChange, len(valid))
+	for n, i := range valid {
+		person := people[i]
+		person.ID, person.Version, person.DeletedAt = ids[n], 1, nil
+		change, err := newPersonChange(model.OperationCreate, audit, nil, &person)
+		if err != nil {
+			return err
+		}
+		changes[n] = change
+	}
+
+	// JSONB передается строкой: COPY кодирует []byte как bytea.
+	err = copyIn(ctx, tx, pq.CopyIn("person_history", "person_id", "operation", "actor", "request_id", "changed_at", "after", "diff"), len(changes), func(n int) []interface{} {
+		change := changes[n]
+		return []interface{}{change.PersonID, change.Operation, change.Actor, change.RequestID, change.ChangedAt, string(change.After), string(change.Diff)}
+	})
+	if err != nil {
+		return err
+	}
+
+	for n, i := range valid {
+		result.IDs[i] = ids[n]
+	}
+	return nil
+}
+
+// createRows создает строки valid по одной в транзакции tx. Каждая строка создается под точкой сохранения,
+// поэтому строка, которую отклонила база данных, откатывается одна и попадает в ошибки результата.
+func (r *Repository) createRows(ctx context.Context, tx *sqlx.Tx, people []model.Person, valid []int, audit model.AuditInfo, result *model.BulkCreateResult) error {
+	txRepo := *r
+	txRepo.tx = tx
+	for _, i := range valid {
+		person := people[i]
+		err := savepoint(ctx, tx, func() error {
+			return txRepo.CreatePerson(ctx, &person, audit)
+		})
+		if isRowError(err) {
+			result.Errors = append(result.Errors, model.BulkRowError{Row: i, Error: err.Error()})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		result.IDs[i] = person.ID
+	}
+	sort.Slice(result.Errors, func(a, b int) bool {
+		return result.Errors[a].Row < result.Errors[b].Row
+	})
+	return nil
+}
+
+// savepoint выполняет fn под точкой сохранения tx. Если fn вернула ошибку, откатываются только ее изменения,
+// и транзакцию можно продолжать.
+func savepoint(ctx context.Context, tx *sqlx.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_create"); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_create"); rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_create")
+	return err
+}
+
+// isRowError сообщает, что база данных отклонила данные строки: нарушено ограничение или значение не подходит
+// колонке. Остальные ошибки, например разрыв соединения, прерывают всю пачку.
+func isRowError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// Младший байт расширенного кода - основной код ошибки.
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_CONSTRAINT, sqlite3.SQLITE_MISMATCH, sqlite3.SQLITE_TOOBIG:
+			return true
+		}
+	}
+	return false
+}
+
+// copyIn загружает count строк, которые возвращает row, запросом COPY query.
+func copyIn(ctx context.Context, tx *sqlx.Tx, query string, count int, row func(n int) []interface{}) error {
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for n := 0; n < count; n++ {
+		if _, err := stmt.ExecContext(ctx, row(n)...); err != nil {
+			return err
+		}
+	}
+	// Вызов без аргументов завершает COPY и возвращает ошибки загрузки.
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
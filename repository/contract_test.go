@@ -0,0 +1,577 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+)
+
+// testStorageContract проверяет, что хранилище, созданное newStorage, соблюдает контракт Storage.
+// newStorage должна возвращать пустое хранилище.
+func testStorageContract(t *testing.T, newStorage func(t *testing.T) Storage) {
+	ctx := context.Background()
+	audit := model.AuditInfo{Actor: "tester", RequestID: "request-1"}
+
+	createPeople := func(t *testing.T, repo Storage) []model.Person {
+		people := []model.Person{
+			{Name: "Ivan", Surname: "Ivanov", Age: 30, Gender: "male", Nationality: "RU"},
+			{Name: "Anna", Surname: "Petrova", Age: 25, Gender: "female", Nationality: "RU"},
+			{Name: "John", Surname: "Smith", Age: 30, Gender: "male", Nationality: "US"},
+		}
+		for i := range people {
+			if err := repo.CreatePerson(ctx, &people[i], audit); err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			if people[i].ID == 0 || people[i].Version != 1 || people[i].CreatedAt.IsZero() || !people[i].UpdatedAt.Equal(people[i].CreatedAt) {
+				t.Fatalf("Expected ID, version 1 and timestamps to be assigned, got %+v", people[i])
+			}
+		}
+		return people
+	}
+
+	t.Run("GetPeople", func(t *testing.T) {
+		repo := newStorage(t)
+		createPeople(t, repo)
+
+		people, err := repo.GetPeople(ctx, model.PersonFilter{
+			Fields: map[string]string{"age": "30"},
+			Sort:   []model.SortField{{Field: "name", Desc: true}},
+			Limit:  10,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if len(people) != 2 || people[0].Name != "John" || people[1].Name != "Ivan" {
+			t.Errorf("Unexpected filtered people: %+v", people)
+		}
+
+		people, err = repo.GetPeople(ctx, model.PersonFilter{Offset: 1, Limit: 1})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if len(people) != 1 || people[0].Name != "Anna" {
+			t.Errorf("Unexpected page: %+v", people)
+		}
+
+		if _, err := repo.GetPeople(ctx, model.PersonFilter{Fields: map[string]string{"id; DROP TABLE people": "1"}, Limit: 10}); err == nil {
+			t.Error("Expected error for unknown filter field")
+		}
+	})
+
+	t.Run("ExportPeople", func(t *testing.T) {
+		repo := newStorage(t)
+		createPeople(t, repo)
+
+		var names []string
+		err := repo.ExportPeople(ctx, model.PersonFilter{
+			Fields: map[string]string{"nationality": "RU"},
+			Sort:   []model.SortField{{Field: "age"}},
+		}, func(person *model.Person) error {
+			names = append(names, person.Name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if !reflect.DeepEqual(names, []string{"Anna", "Ivan"}) {
+			t.Errorf("Unexpected exported people: %v", names)
+		}
+
+		stop := errors.New("stop")
+		calls := 0
+		err = repo.ExportPeople(ctx, model.PersonFilter{}, func(person *model.Person) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("Expected export to stop on callback error, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("UpdateWithVersion", func(t *testing.T) {
+		repo := newStorage(t)
+		person := createPeople(t, repo)[0]
+
+		person.Surname = "Sidorov"
+		if err := repo.UpdatePerson(ctx, &person, audit); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if person.Version != 2 {
+			t.Errorf("Expected version 2, got %d", person.Version)
+		}
+
+		stale := person
+		stale.Version = 1
+		if err := repo.UpdatePerson(ctx, &stale, audit); !errors.Is(err, ErrVersionMismatch) {
+			t.Errorf("Expected ErrVersionMismatch, but got %v", err)
+		}
+
+		version, err := repo.PatchPerson(ctx, int(person.ID), person.Version, map[string]interface{}{"age": 31}, audit)
+		if err != nil || version != 3 {
+			t.Fatalf("Expected version 3 without error, got %d and %v", version, err)
+		}
+		stored, err := repo.GetPersonById(ctx, int(person.ID))
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if stored.Surname != "Sidorov" || stored.Age != 31 || stored.Version != 3 ||
+			!stored.CreatedAt.Equal(person.CreatedAt) || !stored.UpdatedAt.After(stored.CreatedAt) {
+			t.Errorf("Unexpected stored person: %+v", stored)
+		}
+
+		if err := repo.UpdatePerson(ctx, &model.Person{ID: 1000, Name: "Nobody"}, audit); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, but got %v", err)
+		}
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		repo := newStorage(t)
+		person := createPeople(t, repo)[0]
+		id := int(person.ID)
+
+		if err := repo.DeletePerson(ctx, id, 1, audit); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if _, err := repo.GetPersonById(ctx, id); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected deleted person to be hidden, got %v", err)
+		}
+		if people, _ := repo.GetPeople(ctx, model.PersonFilter{Limit: 10}); len(people) != 2 {
+			t.Errorf("Expected 2 people without deleted, got %d", len(people))
+		}
+		if people, _ := repo.GetPeople(ctx, model.PersonFilter{IncludeDeleted: true, Limit: 10}); len(people) != 3 {
+			t.Errorf("Expected 3 people with deleted, got %d", len(people))
+		}
+
+		restored, err := repo.RestorePerson(ctx, id, audit)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if restored.DeletedAt != nil || restored.Version != 3 {
+			t.Errorf("Unexpected restored person: %+v", restored)
+		}
+		if _, err := repo.RestorePerson(ctx, id, audit); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows for not deleted person, got %v", err)
+		}
+
+		if err := repo.DeletePerson(ctx, id, 0, audit); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		// Записи, удаленные позже границы хранения, остаются.
+		if purged, err := repo.PurgeDeletedPeople(ctx, time.Now().Add(-time.Minute), audit); err != nil || purged != 0 {
+			t.Errorf("Expected no purged people before retention, got %d and %v", purged, err)
+		}
+		purged, err := repo.PurgeDeletedPeople(ctx, time.Now().Add(time.Minute), audit)
+		if err != nil || purged != 1 {
+			t.Errorf("Expected 1 purged person without error, got %d and %v", purged, err)
+		}
+		if _, err := repo.RestorePerson(ctx, id, audit); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected purged person to be gone, got %v", err)
+		}
+	})
+
+	t.Run("History", func(t *testing.T) {
+		repo := newStorage(t)
+		person := createPeople(t, repo)[0]
+		id := int(person.ID)
+		created := time.Now()
+		time.Sleep(10 * time.Millisecond)
+
+		person.Surname = "Sidorov"
+		if err := repo.UpdatePerson(ctx, &person, audit); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if err := repo.DeletePerson(ctx, id, 0, audit); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+
+		changes, err := repo.GetPersonHistory(ctx, id)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		operations := []string{model.OperationCreate, model.OperationUpdate, model.OperationDelete}
+		if len(changes) != len(operations) {
+			t.Fatalf("Expected %d changes, got %d", len(operations), len(changes))
+		}
+		for i, change := range changes {
+			if change.Operation != operations[i] || change.Actor != audit.Actor || change.RequestID != audit.RequestID {
+				t.Errorf("Unexpected change %d: %+v", i, change)
+			}
+		}
+		var diff map[string]map[string]interface{}
+		if err := json.Unmarshal(changes[1].Diff, &diff); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		expectedDiff := map[string]map[string]interface{}{
+			"surname": {"from": "Ivanov", "to": "Sidorov"},
+			"version": {"from": float64(1), "to": float64(2)},
+		}
+		if !reflect.DeepEqual(diff, expectedDiff) {
+			t.Errorf("Unexpected diff: %s", changes[1].Diff)
+		}
+
+		change, err := repo.GetPersonChangeAsOf(ctx, id, created)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		past, err := UnmarshalPersonSnapshot(change.After)
+		if err != nil || past.Surname != "Ivanov" || past.ID != person.ID {
+			t.Errorf("Unexpected past state %+v and error %v", past, err)
+		}
+		if _, err := repo.GetPersonChangeAsOf(ctx, id, created.Add(-time.Hour)); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows before creation, got %v", err)
+		}
+	})
+
+	t.Run("HistoryAsOf", func(t *testing.T) {
+		repo := newStorage(t)
+		people := createPeople(t, repo)
+		person := people[0]
+		id := int(person.ID)
+
+		// Моменты после каждого изменения: as_of между ними должен возвращать промежуточное состояние.
+		var moments []time.Time
+		mark := func() {
+			time.Sleep(10 * time.Millisecond)
+			moments = append(moments, time.Now())
+			time.Sleep(10 * time.Millisecond)
+		}
+		mark()
+		for _, surname := range []string{"Sidorov", "Smirnov"} {
+			person.Surname = surname
+			if err := repo.UpdatePerson(ctx, &person, audit); err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			mark()
+		}
+		if err := repo.DeletePerson(ctx, id, 0, audit); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		mark()
+		if _, err := repo.RestorePerson(ctx, id, audit); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+
+		changes, err := repo.GetPersonHistory(ctx, id)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		operations := []string{model.OperationCreate, model.OperationUpdate, model.OperationUpdate, model.OperationDelete, model.OperationRestore}
+		if len(changes) != len(operations) {
+			t.Fatalf("Expected %d changes, got %d", len(operations), len(changes))
+		}
+		for i, change := range changes {
+			if change.Operation != operations[i] || change.PersonID != person.ID {
+				t.Errorf("Unexpected change %d: %+v", i, change)
+			}
+			if i > 0 && (change.ID <= changes[i-1].ID || change.ChangedAt.Before(changes[i-1].ChangedAt)) {
+				t.Errorf("Expected change %d to follow change %d, got %+v after %+v", i, i-1, change, changes[i-1])
+			}
+			snapshot, err := UnmarshalPersonSnapshot(change.After)
+			if err != nil || snapshot.Version != i+1 {
+				t.Errorf("Expected change %d to have version %d, got %+v and error %v", i, i+1, snapshot, err)
+			}
+		}
+
+		expected := []struct {
+			surname string
+			deleted bool
+		}{
+			{"Ivanov", false},
+			{"Sidorov", false},
+			{"Smirnov", false},
+			{"Smirnov", true},
+		}
+		for i, asOf := range moments {
+			change, err := repo.GetPersonChangeAsOf(ctx, id, asOf)
+			if err != nil {
+				t.Fatalf("Expected no error as of moment %d, but got %v", i, err)
+			}
+			past, err := UnmarshalPersonSnapshot(change.After)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			if past.Surname != expected[i].surname || (past.DeletedAt != nil) != expected[i].deleted {
+				t.Errorf("Unexpected state as of moment %d: %+v", i, past)
+			}
+		}
+		change, err := repo.GetPersonChangeAsOf(ctx, id, time.Now().Add(time.Hour))
+		if err != nil || change.Operation != model.OperationRestore {
+			t.Errorf("Expected restore to be the latest change, got %+v and error %v", change, err)
+		}
+
+		other, err := repo.GetPersonHistory(ctx, int(people[1].ID))
+		if err != nil || len(other) != 1 || other[0].Operation != model.OperationCreate {
+			t.Errorf("Expected history of another person to be untouched, got %+v and error %v", other, err)
+		}
+	})
+
+	t.Run("WithTx", func(t *testing.T) {
+		repo := newStorage(t)
+		failure := errors.New("failure")
+
+		err := repo.WithTx(ctx, func(tx Storage) error {
+			if err := tx.CreatePerson(ctx, &model.Person{Name: "Ivan", Surname: "Ivanov"}, audit); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Expected fn error, but got %v", err)
+		}
+		if people, _ := repo.GetPeople(ctx, model.PersonFilter{IncludeDeleted: true, Limit: 10}); len(people) != 0 {
+			t.Errorf("Expected rolled back transaction to create nobody, got %+v", people)
+		}
+
+		var person model.Person
+		err = repo.WithTx(ctx, func(tx Storage) error {
+			person = model.Person{Name: "Anna", Surname: "Petrova"}
+			if err := tx.CreatePerson(ctx, &person, audit); err != nil {
+				return err
+			}
+			person.Age = 25
+			return tx.WithTx(ctx, func(nested Storage) error {
+				return nested.UpdatePerson(ctx, &person, audit)
+			})
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		stored, err := repo.GetPersonById(ctx, int(person.ID))
+		if err != nil || stored.Age != 25 || stored.Version != 2 {
+			t.Errorf("Expected committed person with age 25 and version 2, got %+v and %v", stored, err)
+		}
+		if changes, _ := repo.GetPersonHistory(ctx, int(person.ID)); len(changes) != 2 {
+			t.Errorf("Expected 2 changes, got %d", len(changes))
+		}
+	})
+
+	t.Run("BulkCreatePeople", func(t *testing.T) {
+		repo := newStorage(t)
+
+		result, err := repo.BulkCreatePeople(ctx, []model.Person{
+			{Name: "Ivan", Surname: "Ivanov", Age: 30},
+			{Name: "", Surname: "Nameless"},
+			{Name: "Anna", Surname: "Petrova", Age: 25},
+		}, audit)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if len(result.Errors) != 1 || result.Errors[0].Row != 1 || result.Created() != 2 {
+			t.Fatalf("Unexpected result: %+v", result)
+		}
+		if result.IDs[0] == 0 || result.IDs[1] != 0 || result.IDs[2] == 0 {
+			t.Errorf("Unexpected IDs: %v", result.IDs)
+		}
+
+		stored, err := repo.GetPersonById(ctx, int(result.IDs[2]))
+		if err != nil || stored.Name != "Anna" || stored.Version != 1 {
+			t.Errorf("Unexpected stored person %+v and error %v", stored, err)
+		}
+		if changes, _ := repo.GetPersonHistory(ctx, int(result.IDs[0])); len(changes) != 1 || changes[0].Operation != model.OperationCreate {
+			t.Errorf("Expected create in history, got %+v", changes)
+		}
+	})
+
+	t.Run("Idempotency", func(t *testing.T) {
+		repo := newStorage(t)
+
+		if _, reserved, err := repo.ReserveIdempotencyKey(ctx, "key", "hash", time.Hour, time.Minute); err != nil || !reserved {
+			t.Fatalf("Expected key to be reserved, got %v and %v", reserved, err)
+		}
+		if err := repo.SaveIdempotencyResponse(ctx, "key", 201, "application/json", []byte(`{"id":1}`)); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		record, reserved, err := repo.ReserveIdempotencyKey(ctx, "key", "hash", time.Hour, time.Minute)
+		if err != nil || reserved || record.StatusCode != 201 || string(record.Body) != `{"id":1}` {
+			t.Errorf("Expected saved response, got %+v, %v and %v", record, reserved, err)
+		}
+
+		if _, reserved, _ := repo.ReserveIdempotencyKey(ctx, "expired", "hash", -time.Hour, time.Minute); !reserved {
+			t.Fatal("Expected expired key to be reserved")
+		}
+		if _, reserved, _ := repo.ReserveIdempotencyKey(ctx, "expired", "other", time.Hour, time.Minute); !reserved {
+			t.Error("Expected expired key to be reserved again")
+		}
+
+		if err := repo.ReleaseIdempotencyKey(ctx, "key"); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if _, reserved, _ := repo.ReserveIdempotencyKey(ctx, "key", "hash", -time.Hour, time.Minute); !reserved {
+			t.Error("Expected released key to be reserved again")
+		}
+		if deleted, err := repo.DeleteExpiredIdempotencyKeys(ctx); err != nil || deleted != 1 {
+			t.Errorf("Expected 1 expired key to be deleted, got %d and %v", deleted, err)
+		}
+
+		// Незавершенный запрос с истекшей арендой уступает ключ повтору того же запроса, но не другому запросу.
+		if _, reserved, _ := repo.ReserveIdempotencyKey(ctx, "stuck", "hash", time.Hour, -time.Second); !reserved {
+			t.Fatal("Expected key to be reserved")
+		}
+		if record, reserved, _ := repo.ReserveIdempotencyKey(ctx, "stuck", "other", time.Hour, time.Minute); reserved || record.RequestHash != "hash" {
+			t.Errorf("Expected different request to be rejected, got %+v and %v", record, reserved)
+		}
+		if _, reserved, err := repo.ReserveIdempotencyKey(ctx, "stuck", "hash", time.Hour, time.Minute); err != nil || !reserved {
+			t.Errorf("Expected retry to take over the expired lease, got %v and %v", reserved, err)
+		}
+		if record, reserved, _ := repo.ReserveIdempotencyKey(ctx, "stuck", "hash", time.Hour, time.Minute); reserved || record.StatusCode != 0 {
+			t.Errorf("Expected key to stay reserved within the lease, got %+v and %v", record, reserved)
+		}
+	})
+}
+
+func TestMemoryRepository(t *testing.T) {
+	testStorageContract(t, func(t *testing.T) Storage {
+		return NewMemoryRepository(logging.GetLogger())
+	})
+}
+
+func TestMemoryRepositoryWithTxConflict(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(logging.GetLogger())
+
+	attempts := 0
+	err := repo.WithTx(ctx, func(tx Storage) error {
+		attempts++
+		if attempts == 1 {
+			// Параллельное изменение после начала транзакции заставляет выполнить ее заново.
+			if err := repo.CreatePerson(ctx, &model.Person{Name: "Ivan"}, model.AuditInfo{}); err != nil {
+				return err
+			}
+		}
+		return tx.CreatePerson(ctx, &model.Person{Name: "Anna"}, model.AuditInfo{})
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("Expected transaction to succeed on the second attempt, got %d attempts and %v", attempts, err)
+	}
+	if people, _ := repo.GetPeople(ctx, model.PersonFilter{Limit: 10}); len(people) != 2 {
+		t.Errorf("Expected both people to be stored, got %+v", people)
+	}
+}
+
+func TestMemoryRepositoryTxOptions(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(logging.GetLogger())
+
+	// conflicting выполняет транзакцию, которой мешает параллельное изменение в первых conflicts попытках.
+	conflicting := func(conflicts int) (int, error) {
+		attempts := 0
+		err := repo.WithTx(ctx, func(tx Storage) error {
+			attempts++
+			if attempts <= conflicts {
+				if err := repo.CreatePerson(ctx, &model.Person{Name: "Ivan"}, model.AuditInfo{}); err != nil {
+					return err
+				}
+			}
+			return tx.CreatePerson(ctx, &model.Person{Name: "Anna"}, model.AuditInfo{})
+		})
+		return attempts, err
+	}
+
+	repo.SetTxOptions(TxOptions{Isolation: sql.LevelSerializable, MaxRetries: 2})
+	if attempts, err := conflicting(5); !errors.Is(err, ErrTxConflict) || attempts != 2 {
+		t.Errorf("Expected ErrTxConflict after 2 attempts, got %d attempts and %v", attempts, err)
+	}
+
+	// На уровне read committed транзакция повторяется и после MaxRetries, но не больше maxMemoryTxAttempts раз.
+	repo.SetTxOptions(TxOptions{Isolation: sql.LevelReadCommitted, MaxRetries: 2})
+	if attempts, err := conflicting(5); err != nil || attempts != 6 {
+		t.Errorf("Expected success on the 6th attempt, got %d attempts and %v", attempts, err)
+	}
+	if attempts, err := conflicting(maxMemoryTxAttempts); !errors.Is(err, ErrTxConflict) || attempts != maxMemoryTxAttempts {
+		t.Errorf("Expected ErrTxConflict after %d attempts, got %d attempts and %v", maxMemoryTxAttempts, attempts, err)
+	}
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	testStorageContract(t, func(t *testing.T) Storage {
+		return newSQLiteRepository(t)
+	})
+}
+
+func TestSQLiteBulkCreateRowErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteRepository(t)
+	// Триггер имитирует ограничение, которое база данных проверяет при вставке.
+	repo.db.MustExec(`CREATE TRIGGER people_blocked BEFORE INSERT ON people WHEN NEW.surname = 'Blocked'
+        BEGIN SELECT RAISE(ABORT, 'surname is blocked'); END`)
+
+	result, err := repo.BulkCreatePeople(ctx, []model.Person{
+		{Name: "Ivan", Surname: "Blocked"},
+		{Name: "", Surname: "Nameless"},
+		{Name: "Anna", Surname: "Petrova"},
+	}, model.AuditInfo{})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Row != 0 || !strings.Contains(result.Errors[0].Error, "surname is blocked") ||
+		result.Errors[1].Row != 1 || result.IDs[2] == 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if people, _ := repo.GetPeople(ctx, model.PersonFilter{Limit: 10}); len(people) != 1 || people[0].Name != "Anna" {
+		t.Errorf("Expected only Anna to be stored, got %+v", people)
+	}
+	if changes, _ := repo.GetPersonHistory(ctx, int(result.IDs[2])); len(changes) != 1 {
+		t.Errorf("Expected one change for Anna, got %+v", changes)
+	}
+}
+
+// newSQLiteRepository создает мигрированную базу SQLite во временном каталоге теста.
+func newSQLiteRepository(t *testing.T) *Repository {
+	repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "people.db"), logging.GetLogger())
+	if err != nil {
+		t.Fatalf("Failed to open SQLite: %v", err)
+	}
+	t.Cleanup(func() { repo.db.Close() })
+
+	migrator, err := NewMigrator(repo)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate SQLite: %v", err)
+	}
+	return repo
+}
+
+// TestPostgresRepository запускается, только если TEST_POSTGRES_DSN указывает на базу данных Postgres.
+// Тест применяет к этой базе данных миграции и очищает ее таблицы.
+func TestPostgresRepository(t *testing.T) {
+	testStorageContract(t, func(t *testing.T) Storage {
+		repo := newPostgresRepository(t)
+		repo.db.MustExec("TRUNCATE people, person_history, idempotency_keys RESTART IDENTITY")
+		return repo
+	})
+}
+
+// newPostgresRepository подключается к базе данных из TEST_POSTGRES_DSN и применяет к ней миграции.
+// Без TEST_POSTGRES_DSN тест пропускается.
+func newPostgresRepository(t *testing.T) *Repository {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	repo, err := NewRepository(context.Background(), dsn, PoolOptions{}, logging.GetLogger())
+	if err != nil {
+		t.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+	t.Cleanup(func() { repo.db.Close() })
+
+	migrator, err := NewMigrator(repo)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate Postgres: %v", err)
+	}
+	return repo
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"testProject/internal/model"
+	"testProject/pkg/helpers"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// exportFetchSize количество строк, которое выгрузка из Postgres читает из курсора за раз.
+const exportFetchSize = 1000
+
+// ExportPeople вызывает fn для каждого человека, подходящего под фильтры и сортировку filter, без смещения и лимита.
+// Строки читаются потоком и не накапливаются в памяти; ошибка fn прерывает выгрузку и возвращается.
+// В Postgres строки читаются из серверного курсора в транзакции только для чтения, поэтому выгрузка
+// видит один снимок данных, в SQLite - обычным запросом.
+func (r *Repository) ExportPeople(ctx context.Context, filter model.PersonFilter, fn func(person *model.Person) error) error {
+	r.logger.Debug("Repository: Handling ExportPeople request")
+
+	query, args, err := peopleQuery(filter)
+	if err != nil {
+		return err
+	}
+
+	// Выгрузка, прерванная ошибкой соединения с реплики, повторяется на основной базе данных, только если
+	// строки еще не переданы fn: иначе они были бы выгружены дважды, и повтор возвращает ту же ошибку.
+	delivered, deliver := false, fn
+	fn = func(person *model.Person) error {
+		delivered = true
+		return deliver(person)
+	}
+	var exportErr error
+	export := func(run func() error) error {
+		if !delivered {
+			exportErr = run()
+		}
+		return exportErr
+	}
+
+	if r.driver != driverPostgres {
+		err = r.read(ctx, func(db sqlx.ExtContext) error {
+			return export(func() error { return scanPeople(ctx, db, query, args, fn) })
+		})
+	} else {
+		err = r.readTx(ctx, func(tx *sqlx.Tx) error {
+			return export(func() error { return fetchPeople(ctx, tx, query, args, fn) })
+		})
+	}
+	if err != nil {
+		helpers.LogAndReturnError(r.logger, "error when exporting people:", err)
+	}
+	return err
+}
+
+// fetchPeople читает результат запроса списка людей из серверного курсора Postgres порциями по exportFetchSize
+// и вызывает fn для каждой строки.
+func fetchPeople(ctx context.Context, tx *sqlx.Tx, query string, args []interface{}, fn func(person *model.Person) error) error {
+	if _, err := tx.ExecContext(ctx, "DECLARE people_export NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return err
+	}
+	for {
+		var people []model.Person
+		if err := sqlx.SelectContext(ctx, tx, &people, fmt.Sprintf("FETCH FORWARD %d FROM people_export", exportFetchSize)); err != nil {
+			return err
+		}
+		for i := range people {
+			if err := fn(&people[i]); err != nil {
+				return err
+			}
+		}
+		if len(people) < exportFetchSize {
+			break
+		}
+	}
+	// Курсор закрывается явно на случай, если выгрузка идет во внешней транзакции.
+	_, err := tx.ExecContext(ctx, "CLOSE people_export")
+	return err
+}
+
+// scanPeople выполняет запрос списка людей и вызывает fn для каждой строки по мере чтения.
+func scanPeople(ctx context.Context, db sqlx.QueryerContext, query string, args []interface{}, fn func(person *model.Person) error) error {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var person model.Person
+		if err := rows.StructScan(&person); err != nil {
+			return err
+		}
+		if err := fn(&person); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// readTx выполняет fn в транзакции только для чтения с уровнем изоляции repeatable read на базе данных,
+// которую выбирает read. Внутри WithTx fn выполняется в открытой транзакции.
+func (r *Repository) readTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+
+	return r.read(ctx, func(conn sqlx.ExtContext) error {
+		db, ok := conn.(*sqlx.DB)
+		if !ok {
+			db = r.db
+		}
+		tx, err := db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
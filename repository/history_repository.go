@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/helpers"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// personSnapshot состояние человека в истории изменений вместе с серверными полями.
+type personSnapshot struct {
+	ID      uint `json:"id"`
+	Version int  `json:"version"`
+	*model.Person
+}
+
+// snapshotPerson сериализует состояние person для истории изменений, nil соответствует отсутствию записи.
+func snapshotPerson(person *model.Person) (model.JSONDocument, error) {
+	if person == nil {
+		return nil, nil
+	}
+	return json.Marshal(personSnapshot{ID: person.ID, Version: person.Version, Person: person})
+}
+
+// UnmarshalPersonSnapshot восстанавливает человека из состояния, сохраненного в истории изменений.
+// Для пустого состояния возвращает nil.
+func UnmarshalPersonSnapshot(document model.JSONDocument) (*model.Person, error) {
+	if len(document) == 0 || string(document) == "null" {
+		return nil, nil
+	}
+	snapshot := personSnapshot{Person: &model.Person{}}
+	if err := json.Unmarshal(document, &snapshot); err != nil {
+		return nil, err
+	}
+	snapshot.Person.ID = snapshot.ID
+	snapshot.Person.Version = snapshot.Version
+	return snapshot.Person, nil
+}
+
+// diffSnapshots возвращает поля, значения которых отличаются в before и after, в виде {"поле": {"from": ..., "to": ...}}.
+func diffSnapshots(before, after model.JSONDocument) (model.JSONDocument, error) {
+	var beforeFields, afterFields map[string]interface{}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &beforeFields); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &afterFields); err != nil {
+			return nil, err
+		}
+	}
+
+	diff := make(map[string]map[string]interface{})
+	for field, value := range afterFields {
+		if previous, ok := beforeFields[field]; !ok || !reflect.DeepEqual(previous, value) {
+			diff[field] = map[string]interface{}{"from": beforeFields[field], "to": value}
+		}
+	}
+	for field, previous := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			diff[field] = map[string]interface{}{"from": previous, "to": nil}
+		}
+	}
+	return json.Marshal(diff)
+}
+
+// newPersonChange формирует запись истории об изменении человека с состояния before на after.
+// before равен nil при создании записи, after - при окончательном удалении.
+func newPersonChange(operation string, audit model.AuditInfo, before, after *model.Person) (model.PersonChange, error) {
+	change := model.PersonChange{
+		Operation: operation,
+		Actor:     audit.Actor,
+		RequestID: audit.RequestID,
+		ChangedAt: time.Now().UTC(),
+	}
+	if before != nil {
+		change.PersonID = before.ID
+	} else if after != nil {
+		change.PersonID = after.ID
+	}
+
+	var err error
+	if change.Before, err = snapshotPerson(before); err != nil {
+		return change, err
+	}
+	if change.After, err = snapshotPerson(after); err != nil {
+		return change, err
+	}
+	if change.Diff, err = diffSnapshots(change.Before, change.After); err != nil {
+		return change, err
+	}
+	return change, nil
+}
+
+// recordChange записывает изменение человека в историю в рамках транзакции tx.
+func (r *Repository) recordChange(ctx context.Context, tx *sqlx.Tx, operation string, audit model.AuditInfo, before, after *model.Person) error {
+	change, err := newPersonChange(operation, audit, before, after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO person_history(person_id, operation, actor, request_id, changed_at, before, after, diff)
+        VALUES(:person_id, :operation, :actor, :request_id, :changed_at, :before, :after, :diff)
+    `
+	_, err = tx.NamedExecContext(ctx, query, change)
+	return err
+}
+
+// GetPersonHistory возвращает историю изменений человека в хронологическом порядке.
+func (r *Repository) GetPersonHistory(ctx context.Context, personID int) ([]model.PersonChange, error) {
+	r.logger.Debug("Repository: Handling GetPersonHistory request")
+
+	changes := []model.PersonChange{}
+	err := r.read(ctx, func(db sqlx.ExtContext) error {
+		changes = changes[:0]
+		return sqlx.SelectContext(ctx, db, &changes, "SELECT * FROM person_history WHERE person_id = $1 ORDER BY changed_at, id", personID)
+	})
+	if err != nil {
+		helpers.LogAndReturnError(r.logger, "error when querying the database:", err)
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetPersonChangeAsOf возвращает последнее изменение человека, сделанное не позже asOf.
+// Возвращает sql.ErrNoRows, если к этому моменту записи еще не было.
+func (r *Repository) GetPersonChangeAsOf(ctx context.Context, personID int, asOf time.Time) (*model.PersonChange, error) {
+	r.logger.Debug("Repository: Handling GetPersonChangeAsOf request")
+
+	query := `SELECT * FROM person_history WHERE person_id = $1 AND changed_at <= $2 ORDER BY changed_at DESC, id DESC LIMIT 1`
+
+	var change model.PersonChange
+	err := r.read(ctx, func(db sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, db, &change, query, personID, asOf.UTC())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"testProject/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ReserveIdempotencyKey резервирует ключ идемпотентности за запросом с хешем requestHash на время ttl.
+// Возвращает true, если ключ свободен и зарезервирован, иначе возвращает уже сохраненную запись.
+// Просроченная запись с тем же ключом удаляется перед резервированием.
+// Незавершенный запрос владеет ключом в течение lease: если ответ за это время не сохранен, например процесс
+// остановился, ключ резервирует повтор того же запроса.
+func (r *Repository) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (*model.IdempotencyRecord, bool, error) {
+	r.logger.Debug("Repository: Handling ReserveIdempotencyKey request")
+
+	now := r.now()
+	if _, err := r.conn().ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND expires_at < $2", key, now); err != nil {
+		return nil, false, err
+	}
+
+	query := `
+        INSERT INTO idempotency_keys(key, request_hash, created_at, expires_at, locked_until)
+        VALUES($1, $2, $3, $4, $5)
+        ON CONFLICT (key) DO NOTHING
+    `
+
+	result, err := r.conn().ExecContext(ctx, query, key, requestHash, now, now.Add(ttl), now.Add(lease))
+	if err != nil {
+		return nil, false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	if rowsAffected == 1 {
+		return nil, true, nil
+	}
+
+	takeOver := `
+        UPDATE idempotency_keys SET locked_until = $3, expires_at = $4
+        WHERE key = $1 AND request_hash = $2 AND status_code = 0 AND (locked_until IS NULL OR locked_until < $5)
+    `
+	result, err = r.conn().ExecContext(ctx, takeOver, key, requestHash, now.Add(lease), now.Add(ttl), now)
+	if err != nil {
+		return nil, false, err
+	}
+	if rowsAffected, err = result.RowsAffected(); err != nil {
+		return nil, false, err
+	}
+	if rowsAffected == 1 {
+		r.logger.Warnf("Idempotency key %q lease expired before the response was saved, retrying the request", key)
+		return nil, true, nil
+	}
+
+	var record model.IdempotencyRecord
+	err = sqlx.GetContext(ctx, r.conn(), &record, "SELECT * FROM idempotency_keys WHERE key = $1", key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Запись успели удалить между INSERT и SELECT, пробуем еще раз.
+		return r.ReserveIdempotencyKey(ctx, key, requestHash, ttl, lease)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &record, false, nil
+}
+
+// SaveIdempotencyResponse сохраняет ответ для зарезервированного ключа идемпотентности.
+func (r *Repository) SaveIdempotencyResponse(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = $2, content_type = $3, body = $4 WHERE key = $1`
+
+	if _, err := r.conn().ExecContext(ctx, query, key, statusCode, contentType, body); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey удаляет резервирование ключа, чтобы клиент мог повторить запрос.
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := r.conn().ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1", key); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys удаляет просроченные ключи идемпотентности и возвращает их количество.
+func (r *Repository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := r.conn().ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < $1", r.now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
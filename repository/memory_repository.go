@@ -0,0 +1,596 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"testProject/internal/model"
+	"testProject/pkg/logging"
+)
+
+// MemoryRepository потокобезопасное хранилище в памяти с той же семантикой, что и Repository.
+// Используется в тестах и для локального запуска без Postgres, данные теряются при остановке.
+type MemoryRepository struct {
+	mu           sync.RWMutex
+	people       map[uint]model.Person
+	nextID       uint
+	history      []model.PersonChange
+	nextChangeID int64
+	idempotency  map[string]model.IdempotencyRecord
+	revision     int64 // увеличивается при каждом изменении, по нему WithTx находит конфликты
+	txOptions    TxOptions
+	logger       *logging.Logger
+}
+
+// NewMemoryRepository создает пустое хранилище в памяти с переданным логгером.
+func NewMemoryRepository(logger *logging.Logger) *MemoryRepository {
+	return &MemoryRepository{
+		people:      make(map[uint]model.Person),
+		idempotency: make(map[string]model.IdempotencyRecord),
+		txOptions:   DefaultTxOptions,
+		logger:      logger,
+	}
+}
+
+// SetTxOptions задает уровень изоляции и количество попыток для транзакций WithTx.
+func (r *MemoryRepository) SetTxOptions(opts TxOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txOptions = opts
+}
+
+// CreatePerson создает новую запись о человеке и записывает создание в историю изменений.
+func (r *MemoryRepository) CreatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error {
+	r.logger.Debug("MemoryRepository: Handling CreatePerson request")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	created := copyPerson(*person)
+	created.ID = r.nextID
+	created.Version = 1
+	created.DeletedAt = nil
+	created.CreatedAt = time.Now().UTC()
+	created.UpdatedAt = created.CreatedAt
+
+	if err := r.recordChange(model.OperationCreate, audit, nil, &created); err != nil {
+		return err
+	}
+	r.people[created.ID] = created
+
+	person.ID, person.Version, person.DeletedAt = created.ID, created.Version, nil
+	person.CreatedAt, person.UpdatedAt = created.CreatedAt, created.UpdatedAt
+	return nil
+}
+
+// BulkCreatePeople создает корректные строки people одной транзакцией, некорректные возвращаются в ошибках результата.
+func (r *MemoryRepository) BulkCreatePeople(ctx context.Context, people []model.Person, audit model.AuditInfo) (*model.BulkCreateResult, error) {
+	return bulkCreateByRow(ctx, r, people, audit)
+}
+
+// GetPeople возвращает список людей с учетом переданных фильтров, сортировки, смещения и лимита.
+// Удаленные записи возвращаются только при filter.IncludeDeleted.
+func (r *MemoryRepository) GetPeople(ctx context.Context, filter model.PersonFilter) ([]model.Person, error) {
+	for column := range filter.Fields {
+		if !personColumns[column] {
+			return nil, fmt.Errorf("unknown filter field %q", column)
+		}
+	}
+	for _, field := range filter.Sort {
+		if field.Field != "id" && !personColumns[field.Field] {
+			return nil, fmt.Errorf("unknown sort field %q", field.Field)
+		}
+	}
+	if filter.Offset < 0 || filter.Limit < 0 {
+		return nil, fmt.Errorf("offset and limit must not be negative")
+	}
+
+	r.mu.RLock()
+	people := make([]model.Person, 0, len(r.people))
+	for _, person := range r.people {
+		if matchesFilter(&person, filter) {
+			people = append(people, copyPerson(person))
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(people, func(i, j int) bool {
+		for _, field := range filter.Sort {
+			if cmp := comparePersonField(&people[i], &people[j], field.Field); cmp != 0 {
+				return (cmp < 0) != field.Desc
+			}
+		}
+		return people[i].ID < people[j].ID
+	})
+
+	if filter.Offset >= len(people) {
+		return []model.Person{}, nil
+	}
+	people = people[filter.Offset:]
+	if filter.Limit < len(people) {
+		people = people[:filter.Limit]
+	}
+	return people, nil
+}
+
+// ExportPeople вызывает fn для каждого человека, подходящего под фильтры и сортировку filter, без смещения и лимита.
+// Выгрузка работает со снимком списка на момент вызова.
+func (r *MemoryRepository) ExportPeople(ctx context.Context, filter model.PersonFilter, fn func(person *model.Person) error) error {
+	filter.Offset, filter.Limit = 0, math.MaxInt
+	people, err := r.GetPeople(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for i := range people {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&people[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPersonById возвращает информацию о неудаленном человеке по его идентификатору.
+func (r *MemoryRepository) GetPersonById(ctx context.Context, id int) (*model.Person, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	person, ok := r.people[uint(id)]
+	if !ok || person.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	person = copyPerson(person)
+	return &person, nil
+}
+
+// UpdatePerson обновляет информацию о человеке, увеличивает его версию и записывает изменение в историю.
+// Если person.Version не равна нулю, обновление выполняется только при совпадении версии,
+// иначе возвращается ErrVersionMismatch. После обновления person.Version содержит новую версию.
+func (r *MemoryRepository) UpdatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, err := r.activePerson(int(person.ID))
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(&before, person.Version); err != nil {
+		return err
+	}
+
+	after := copyPerson(before)
+	after.Name, after.Surname, after.Patronymic = person.Name, person.Surname, person.Patronymic
+	after.Age, after.Gender, after.Nationality = person.Age, person.Gender, person.Nationality
+	after.Version++
+	after.UpdatedAt = time.Now().UTC()
+
+	if err := r.recordChange(model.OperationUpdate, audit, &before, &after); err != nil {
+		return err
+	}
+	r.people[after.ID] = after
+
+	person.Version, person.CreatedAt, person.UpdatedAt, person.EnrichedAt = after.Version, after.CreatedAt, after.UpdatedAt, after.EnrichedAt
+	return nil
+}
+
+// PatchPerson обновляет у человека только переданные поля fields, записывает изменение в историю
+// и возвращает его новую версию. Возвращает sql.ErrNoRows, если человека с таким id нет, и ErrVersionMismatch,
+// если version не равна нулю и не совпадает с текущей версией.
+func (r *MemoryRepository) PatchPerson(ctx context.Context, id, version int, fields map[string]interface{}, audit model.AuditInfo) (int, error) {
+	for column := range fields {
+		if !personColumns[column] {
+			return 0, fmt.Errorf("column %q cannot be patched", column)
+		}
+	}
+	if len(fields) == 0 {
+		return version, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, err := r.activePerson(id)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkVersion(&before, version); err != nil {
+		return 0, err
+	}
+
+	after := copyPerson(before)
+	for column, value := range fields {
+		if err := setPersonField(&after, column, value); err != nil {
+			return 0, err
+		}
+	}
+	after.Version++
+	after.UpdatedAt = time.Now().UTC()
+
+	if err := r.recordChange(model.OperationUpdate, audit, &before, &after); err != nil {
+		return 0, err
+	}
+	r.people[after.ID] = after
+	return after.Version, nil
+}
+
+// DeletePerson помечает запись о человеке удаленной по его id и записывает удаление в историю.
+// Если version не равна нулю, запись удаляется только при совпадении версии, иначе возвращается ErrVersionMismatch.
+func (r *MemoryRepository) DeletePerson(ctx context.Context, id, version int, audit model.AuditInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, err := r.activePerson(id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(&before, version); err != nil {
+		return err
+	}
+
+	after := copyPerson(before)
+	deletedAt := time.Now().UTC()
+	after.DeletedAt = &deletedAt
+	after.UpdatedAt = deletedAt
+	after.Version++
+
+	if err := r.recordChange(model.OperationDelete, audit, &before, &after); err != nil {
+		return err
+	}
+	r.people[after.ID] = after
+	return nil
+}
+
+// RestorePerson восстанавливает удаленную запись о человеке, записывает восстановление в историю и возвращает запись.
+// Возвращает sql.ErrNoRows, если удаленного человека с таким id нет.
+func (r *MemoryRepository) RestorePerson(ctx context.Context, id int, audit model.AuditInfo) (*model.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, ok := r.people[uint(id)]
+	if !ok || before.DeletedAt == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	after := copyPerson(before)
+	after.DeletedAt = nil
+	after.UpdatedAt = time.Now().UTC()
+	after.Version++
+
+	if err := r.recordChange(model.OperationRestore, audit, &before, &after); err != nil {
+		return nil, err
+	}
+	r.people[after.ID] = after
+
+	restored := copyPerson(after)
+	return &restored, nil
+}
+
+// PurgeDeletedPeople окончательно удаляет записи, помеченные удаленными раньше deletedBefore,
+// и записывает удаление каждой из них в историю. Возвращает количество удаленных записей.
+func (r *MemoryRepository) PurgeDeletedPeople(ctx context.Context, deletedBefore time.Time, audit model.AuditInfo) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, person := range r.people {
+		if person.DeletedAt == nil || !person.DeletedAt.Before(deletedBefore) {
+			continue
+		}
+		if err := r.recordChange(model.OperationPurge, audit, &person, nil); err != nil {
+			return purged, err
+		}
+		delete(r.people, id)
+		purged++
+	}
+	return purged, nil
+}
+
+// GetPersonHistory возвращает историю изменений человека в хронологическом порядке.
+func (r *MemoryRepository) GetPersonHistory(ctx context.Context, personID int) ([]model.PersonChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	changes := []model.PersonChange{}
+	for _, change := range r.history {
+		if change.PersonID == uint(personID) {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// GetPersonChangeAsOf возвращает последнее изменение человека, сделанное не позже asOf.
+// Возвращает sql.ErrNoRows, если к этому моменту записи еще не было.
+func (r *MemoryRepository) GetPersonChangeAsOf(ctx context.Context, personID int, asOf time.Time) (*model.PersonChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.history) - 1; i >= 0; i-- {
+		change := r.history[i]
+		if change.PersonID == uint(personID) && !change.ChangedAt.After(asOf) {
+			return &change, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// ReserveIdempotencyKey резервирует ключ идемпотентности за запросом с хешем requestHash.
+// Возвращает true, если ключ свободен и зарезервирован, иначе возвращает уже сохраненную запись.
+// Ключ незавершенного запроса, аренда lease которого истекла, резервирует повтор того же запроса.
+func (r *MemoryRepository) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (*model.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	lockedUntil := now.Add(lease)
+	if record, ok := r.idempotency[key]; ok && !record.ExpiresAt.Before(now) {
+		if record.StatusCode == 0 && record.RequestHash == requestHash && (record.LockedUntil == nil || record.LockedUntil.Before(now)) {
+			record.LockedUntil, record.ExpiresAt = &lockedUntil, now.Add(ttl)
+			r.idempotency[key] = record
+			r.revision++
+			return nil, true, nil
+		}
+		record.Body = append([]byte(nil), record.Body...)
+		return &record, false, nil
+	}
+
+	r.idempotency[key] = model.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+		LockedUntil: &lockedUntil,
+	}
+	r.revision++
+	return nil, true, nil
+}
+
+// SaveIdempotencyResponse сохраняет ответ для зарезервированного ключа идемпотентности.
+func (r *MemoryRepository) SaveIdempotencyResponse(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.idempotency[key]
+	if !ok {
+		return nil
+	}
+	record.StatusCode, record.ContentType, record.Body = statusCode, contentType, append([]byte(nil), body...)
+	r.idempotency[key] = record
+	r.revision++
+	return nil
+}
+
+// ReleaseIdempotencyKey удаляет резервирование ключа, чтобы клиент мог повторить запрос.
+func (r *MemoryRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.idempotency[key]; ok {
+		delete(r.idempotency, key)
+		r.revision++
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys удаляет просроченные ключи идемпотентности и возвращает их количество.
+func (r *MemoryRepository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	var deleted int64
+	for key, record := range r.idempotency {
+		if record.ExpiresAt.Before(now) {
+			delete(r.idempotency, key)
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		r.revision++
+	}
+	return deleted, nil
+}
+
+// Close ничего не делает: у хранилища в памяти нет соединений.
+func (r *MemoryRepository) Close() error {
+	return nil
+}
+
+// Ping всегда успешен: хранилище в памяти доступно, пока работает процесс.
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// maxMemoryTxAttempts наибольшее число попыток WithTx на уровнях слабее repeatable read,
+// чтобы постоянные параллельные изменения не повторяли транзакцию бесконечно.
+const maxMemoryTxAttempts = 100
+
+// WithTx выполняет fn над копией хранилища и применяет изменения копии, если fn вернула nil.
+// Если за это время хранилище изменили параллельно, fn выполняется заново на свежей копии.
+// На уровнях repeatable read и serializable конфликт приводит к ErrTxConflict после txOptions.MaxRetries
+// попыток. На более слабых уровнях Postgres транзакцию не отменяет, поэтому fn повторяется до
+// maxMemoryTxAttempts попыток или отмены ctx.
+func (r *MemoryRepository) WithTx(ctx context.Context, fn func(repo Storage) error) error {
+	for attempt := 1; ; attempt++ {
+		r.mu.RLock()
+		tx := r.clone()
+		base := r.revision
+		opts := r.txOptions
+		r.mu.RUnlock()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		if r.revision == base {
+			r.people, r.nextID = tx.people, tx.nextID
+			r.history, r.nextChangeID = tx.history, tx.nextChangeID
+			r.idempotency = tx.idempotency
+			r.revision++
+			r.mu.Unlock()
+			return nil
+		}
+		r.mu.Unlock()
+
+		maxAttempts := maxMemoryTxAttempts
+		if opts.Isolation >= sql.LevelRepeatableRead {
+			maxAttempts = opts.MaxRetries
+		}
+		if attempt >= maxAttempts {
+			return ErrTxConflict
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// clone возвращает независимую копию хранилища. Вызывается под r.mu.
+func (r *MemoryRepository) clone() *MemoryRepository {
+	tx := &MemoryRepository{
+		people:       make(map[uint]model.Person, len(r.people)),
+		nextID:       r.nextID,
+		history:      append([]model.PersonChange(nil), r.history...),
+		nextChangeID: r.nextChangeID,
+		idempotency:  make(map[string]model.IdempotencyRecord, len(r.idempotency)),
+		revision:     r.revision,
+		txOptions:    r.txOptions,
+		logger:       r.logger,
+	}
+	for id, person := range r.people {
+		tx.people[id] = copyPerson(person)
+	}
+	for key, record := range r.idempotency {
+		tx.idempotency[key] = record
+	}
+	return tx
+}
+
+// activePerson возвращает неудаленную запись о человеке или sql.ErrNoRows. Вызывается под r.mu.
+func (r *MemoryRepository) activePerson(id int) (model.Person, error) {
+	person, ok := r.people[uint(id)]
+	if !ok || person.DeletedAt != nil {
+		return model.Person{}, sql.ErrNoRows
+	}
+	return copyPerson(person), nil
+}
+
+// recordChange добавляет изменение человека в историю. Вызывается под r.mu.
+func (r *MemoryRepository) recordChange(operation string, audit model.AuditInfo, before, after *model.Person) error {
+	change, err := newPersonChange(operation, audit, before, after)
+	if err != nil {
+		return err
+	}
+	r.nextChangeID++
+	change.ID = r.nextChangeID
+	r.history = append(r.history, change)
+	r.revision++
+	return nil
+}
+
+// copyPerson возвращает копию person, не разделяющую с ней DeletedAt.
+func copyPerson(person model.Person) model.Person {
+	if person.DeletedAt != nil {
+		deletedAt := *person.DeletedAt
+		person.DeletedAt = &deletedAt
+	}
+	if person.EnrichedAt != nil {
+		enrichedAt := *person.EnrichedAt
+		person.EnrichedAt = &enrichedAt
+	}
+	return person
+}
+
+// personField возвращает значение колонки column у person.
+func personField(person *model.Person, column string) interface{} {
+	switch column {
+	case "id":
+		return int(person.ID)
+	case "name":
+		return person.Name
+	case "surname":
+		return person.Surname
+	case "patronymic":
+		return person.Patronymic
+	case "age":
+		return person.Age
+	case "gender":
+		return person.Gender
+	case "nationality":
+		return person.Nationality
+	}
+	return nil
+}
+
+// setPersonField присваивает колонке column у person значение value.
+func setPersonField(person *model.Person, column string, value interface{}) error {
+	if column == "age" {
+		age, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("column %q expects int, got %T", column, value)
+		}
+		person.Age = age
+		return nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("column %q expects string, got %T", column, value)
+	}
+	switch column {
+	case "name":
+		person.Name = text
+	case "surname":
+		person.Surname = text
+	case "patronymic":
+		person.Patronymic = text
+	case "gender":
+		person.Gender = text
+	case "nationality":
+		person.Nationality = text
+	default:
+		return fmt.Errorf("column %q cannot be patched", column)
+	}
+	return nil
+}
+
+// matchesFilter сообщает, подходит ли person под фильтр filter.
+func matchesFilter(person *model.Person, filter model.PersonFilter) bool {
+	if person.DeletedAt != nil && !filter.IncludeDeleted {
+		return false
+	}
+	for column, value := range filter.Fields {
+		if fmt.Sprint(personField(person, column)) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// comparePersonField сравнивает значения колонки column у a и b.
+func comparePersonField(a, b *model.Person, column string) int {
+	switch left := personField(a, column).(type) {
+	case int:
+		right := personField(b, column).(int)
+		switch {
+		case left < right:
+			return -1
+		case left > right:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(left, personField(b, column).(string))
+	}
+	return 0
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"testProject/migrations"
+	"testProject/pkg/logging"
+)
+
+// migrationLockID ключ advisory lock Postgres, под которым выполняются миграции.
+// Реплики, запущенные одновременно, применяют миграции по очереди.
+const migrationLockID = 20240301
+
+// ErrDirtyMigration миграция, запущенная вне транзакции (например, golang-migrate), завершилась с ошибкой.
+// Состояние схемы нужно проверить вручную и записать командой force.
+var ErrDirtyMigration = errors.New("database schema is dirty, fix it manually and run force")
+
+// Migration одна версия схемы: SQL для применения и отката.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus состояние миграции в базе данных.
+type MigrationStatus struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Migrator применяет встроенные миграции и хранит текущую версию в таблице schema_migrations.
+// Формат таблицы совпадает с golang-migrate, поэтому базы, размеченные им, продолжают мигрировать без изменений.
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+	logger     *logging.Logger
+}
+
+// NewMigrator создает migrator для базы данных repository с миграциями для ее драйвера.
+func NewMigrator(repo *Repository) (*Migrator, error) {
+	var source fs.FS = migrations.Postgres
+	if repo.driver == driverSQLite {
+		sub, err := fs.Sub(migrations.SQLite, "sqlite")
+		if err != nil {
+			return nil, err
+		}
+		source = sub
+	}
+
+	list, err := loadMigrations(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: repo.db.DB, driver: repo.driver, migrations: list, logger: repo.logger}, nil
+}
+
+// loadMigrations читает файлы вида 000001_name.up.sql и 000001_name.down.sql и упорядочивает их по версии.
+func loadMigrations(source fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(source, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, file := range files {
+		name := path.Base(file)
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction, name = "up", strings.TrimSuffix(name, ".up.sql")
+		case strings.HasSuffix(name, ".down.sql"):
+			direction, name = "down", strings.TrimSuffix(name, ".down.sql")
+		default:
+			return nil, fmt.Errorf("unexpected migration file %s", file)
+		}
+
+		parts := strings.SplitN(name, "_", 2)
+		version, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+
+		query, err := fs.ReadFile(source, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: parts[1]}
+			byVersion[uint(version)] = migration
+		}
+		if direction == "up" {
+			migration.Up = string(query)
+		} else {
+			migration.Down = string(query)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", migration.Version)
+		}
+		list = append(list, *migration)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Up применяет все непримененные миграции и возвращает их количество.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if migration.Version <= current {
+				continue
+			}
+			m.logger.Infof("Applying migration %d_%s", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration.Up, int64(migration.Version)); err != nil {
+				return fmt.Errorf("migration %d_%s: %v", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает n последних примененных миграций и возвращает количество откаченных.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	reverted := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && reverted < n; i-- {
+			migration := m.migrations[i]
+			if migration.Version > current {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+
+			// После отката версией схемы становится предыдущая миграция, -1 означает пустую схему.
+			previous := int64(-1)
+			if i > 0 {
+				previous = int64(m.migrations[i-1].Version)
+			}
+			m.logger.Infof("Reverting migration %d_%s", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration.Down, previous); err != nil {
+				return fmt.Errorf("migration %d_%s: %v", migration.Version, migration.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status возвращает текущую версию схемы, признак незавершенной миграции и состояние всех миграций.
+func (m *Migrator) Status(ctx context.Context) (uint, bool, []MigrationStatus, error) {
+	var (
+		version  uint
+		dirty    bool
+		statuses []MigrationStatus
+	)
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		current, isDirty, err := m.readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if current > 0 {
+			version = uint(current)
+		}
+		dirty = isDirty
+		for _, migration := range m.migrations {
+			statuses = append(statuses, MigrationStatus{
+				Version: migration.Version,
+				Name:    migration.Name,
+				Applied: int64(migration.Version) <= current,
+			})
+		}
+		return nil
+	})
+	return version, dirty, statuses, err
+}
+
+// Force записывает version как текущую версию схемы и снимает признак незавершенной миграции, не выполняя SQL.
+// Используется после ручного исправления схемы; version -1 означает пустую схему.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := setVersion(ctx, tx, version); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// apply выполняет query и записывает version в одной транзакции.
+// Postgres и SQLite откатывают DDL вместе с транзакцией, поэтому ошибка оставляет схему в прежней версии.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, query string, version int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := setVersion(ctx, tx, version); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// setVersion заменяет единственную строку schema_migrations, version -1 очищает таблицу.
+func setVersion(ctx context.Context, tx *sql.Tx, version int64) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if version < 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)", version)
+	return err
+}
+
+// currentVersion возвращает текущую версию схемы или ErrDirtyMigration. Пустой схеме соответствует 0.
+func (m *Migrator) currentVersion(ctx context.Context, conn *sql.Conn) (uint, error) {
+	version, dirty, err := m.readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w: version %d", ErrDirtyMigration, version)
+	}
+	if version < 0 {
+		return 0, nil
+	}
+	return uint(version), nil
+}
+
+// readVersion читает schema_migrations, создавая ее при необходимости. Пустой таблице соответствует -1.
+func (m *Migrator) readVersion(ctx context.Context, conn *sql.Conn) (int64, bool, error) {
+	create := "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)"
+	if _, err := conn.ExecContext(ctx, create); err != nil {
+		return 0, false, err
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err := conn.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, false, nil
+	}
+	return version, dirty, err
+}
+
+// withLock выполняет fn на отдельном соединении под advisory lock Postgres.
+// SQLite работает через одно соединение, поэтому отдельная блокировка ему не нужна.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.driver == driverPostgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %v", err)
+		}
+		defer func() {
+			// Блокировка снимается и при закрытии соединения, ошибку достаточно записать в лог.
+			if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+				m.logger.Errorf("Failed to release migration lock: %v", err)
+			}
+		}()
+	}
+	return fn(conn)
+}
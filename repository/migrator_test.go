@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrator(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		testMigrator(t, newSQLiteRepository(t))
+	})
+	// Postgres проверяется, только если задан TEST_POSTGRES_DSN, см. TestPostgresRepository.
+	t.Run("Postgres", func(t *testing.T) {
+		testMigrator(t, newPostgresRepository(t))
+	})
+}
+
+// testMigrator проверяет миграции мигрированной базы данных repo и оставляет ее мигрированной.
+func testMigrator(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+	migrator, err := NewMigrator(repo)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	latest := migrator.migrations[len(migrator.migrations)-1].Version
+
+	if applied, err := migrator.Up(ctx); err != nil || applied != 0 {
+		t.Errorf("Expected migrated database to be up to date, got %d and %v", applied, err)
+	}
+
+	if reverted, err := migrator.Down(ctx, 1); err != nil || reverted != 1 {
+		t.Fatalf("Expected 1 reverted migration, got %d and %v", reverted, err)
+	}
+	version, dirty, statuses, err := migrator.Status(ctx)
+	if err != nil || dirty || version != latest-1 {
+		t.Fatalf("Expected version %d, got %d, dirty %v and %v", latest-1, version, dirty, err)
+	}
+	if statuses[len(statuses)-1].Applied || !statuses[0].Applied {
+		t.Errorf("Unexpected statuses: %+v", statuses)
+	}
+
+	if applied, err := migrator.Up(ctx); err != nil || applied != 1 {
+		t.Errorf("Expected 1 applied migration, got %d and %v", applied, err)
+	}
+
+	if err := migrator.Force(ctx, -1); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if version, _, _, _ := migrator.Status(ctx); version != 0 {
+		t.Errorf("Expected empty schema version after force, got %d", version)
+	}
+
+	if err := migrator.Force(ctx, int64(latest)); err != nil {
+		t.Fatalf("Failed to restore schema version: %v", err)
+	}
+}
@@ -1,67 +1,87 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"testProject/internal/model"
 	"testProject/pkg/helpers"
 	"testProject/pkg/logging"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 var ErrRowsAffected = errors.New("Error getting RowsAffected. This may indicate a problem with the underlying database or an issue with the query execution. Please check the database connection and the correctness of the query.")
 var ErrNamedExec = errors.New("Error in NamedExec. This may indicate a problem with the underlying database or an issue with the query execution. Please check the database connection and the correctness of the query.")
+var ErrVersionMismatch = errors.New("person version does not match the expected version")
 
 // repository представляет собой сервис для работы с БД.
+// driver определяет диалект SQL: Postgres (driverPostgres) или SQLite (driverSQLite).
+// Если tx не nil, все запросы выполняются в этой транзакции (см. WithTx).
+// Чтения без транзакции распределяются по replicas, если они подключены (см. AddReplicas).
 type Repository struct {
-	db     *sqlx.DB
-	logger *logging.Logger
+	db        *sqlx.DB
+	tx        *sqlx.Tx
+	replicas  *replicaSet
+	driver    string
+	txOptions TxOptions
+	logger    *logging.Logger
 }
 
-// NewRepository создает новый экземпляр repository с переданным url и логгером в конструкторе.
-func NewRepository(databaseURL string, logging *logging.Logger) (*Repository, error) {
-	db, err := sqlx.Connect("postgres", databaseURL)
+// NewRepository создает новый экземпляр repository с переданным url, настройками пула и логгером в конструкторе.
+// Если база данных еще недоступна, подключение повторяется с растущей паузой до отмены ctx.
+func NewRepository(ctx context.Context, databaseURL string, pool PoolOptions, logging *logging.Logger) (*Repository, error) {
+	db, err := connectWithRetry(ctx, "postgres", databaseURL, logging)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to the database: %v", err)
 	}
-	return &Repository{db: db, logger: logging}, nil
+	pool.apply(db)
+	return &Repository{db: db, driver: driverPostgres, txOptions: DefaultTxOptions, logger: logging}, nil
 }
 
-// CreatePerson создает новую запись о человеке в базе данных.
-func (r *Repository) CreatePerson(person *model.Person) error {
+// CreatePerson создает новую запись о человеке в базе данных и записывает создание в историю изменений.
+// Идентификатор, версия и время создания записи проставляются в person.
+func (r *Repository) CreatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error {
 	r.logger.Debug("Repository: Handling CreatePerson request")
 
 	query := `
-        INSERT INTO people(name, surname, patronymic, age, gender, nationality) 
-        VALUES($1, $2, $3, $4, $5, $6) 
-        RETURNING id
+        INSERT INTO people(name, surname, patronymic, age, gender, nationality, created_at, updated_at, enriched_at) 
+        VALUES($1, $2, $3, $4, $5, $6, $7, $7, $8) 
+        RETURNING id, version
     `
 
-	err := r.db.QueryRow(query, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nationality).Scan(&person.ID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		now := r.now()
+		err := tx.QueryRowContext(ctx, query, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nationality, now, person.EnrichedAt).Scan(&person.ID, &person.Version)
+		if err != nil {
+			return err
+		}
+		person.CreatedAt, person.UpdatedAt = now, now
+		return r.recordChange(ctx, tx, model.OperationCreate, audit, nil, person)
+	})
 
 }
 
-// GetPeople возвращает список людей с учетом переданных фильтров, смещения и лимита.
-func (r *Repository) GetPeople(filters map[string]interface{}, offset, limit int) ([]model.Person, error) {
-	query := `SELECT * FROM people WHERE`
-	args := make([]interface{}, 0)
-	for key, value := range filters {
-		query += key + "=? AND "
-		args = append(args, value)
+// GetPeople возвращает список людей с учетом переданных фильтров, сортировки, смещения и лимита.
+// Удаленные записи возвращаются только при filter.IncludeDeleted.
+func (r *Repository) GetPeople(ctx context.Context, filter model.PersonFilter) ([]model.Person, error) {
+	query, args, err := peopleQuery(filter)
+	if err != nil {
+		return nil, err
 	}
-	query += "1=1 LIMIT $1 OFFSET $2"
-
-	args = append(args, limit, offset)
+	args = append(args, filter.Limit, filter.Offset)
+	query = fmt.Sprintf("%s LIMIT $%d OFFSET $%d", query, len(args)-1, len(args))
 
 	var people []model.Person
-	if err := r.db.Select(&people, query, args...); err != nil {
+	err = r.read(ctx, func(db sqlx.ExtContext) error {
+		people = nil
+		return sqlx.SelectContext(ctx, db, &people, query, args...)
+	})
+	if err != nil {
 		helpers.LogAndReturnError(r.logger, "error when querying the database:", err)
 		return nil, err
 	}
@@ -69,10 +89,53 @@ func (r *Repository) GetPeople(filters map[string]interface{}, offset, limit int
 	return people, nil
 }
 
-// GetPersonById возвращает информацию о человеке по его идентификатору.
-func (r *Repository) GetPersonById(id int) (*model.Person, error) {
+// peopleQuery возвращает запрос списка людей с фильтрами и сортировкой filter без смещения и лимита.
+func peopleQuery(filter model.PersonFilter) (string, []interface{}, error) {
+	columns := make([]string, 0, len(filter.Fields))
+	for column := range filter.Fields {
+		if !personColumns[column] {
+			return "", nil, fmt.Errorf("unknown filter field %q", column)
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	conditions := make([]string, 0, len(columns)+1)
+	args := make([]interface{}, 0, len(columns)+2)
+	for _, column := range columns {
+		args = append(args, filter.Fields[column])
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if len(conditions) == 0 {
+		conditions = append(conditions, "TRUE")
+	}
+
+	orderBy := make([]string, 0, len(filter.Sort)+1)
+	for _, field := range filter.Sort {
+		if field.Field != "id" && !personColumns[field.Field] {
+			return "", nil, fmt.Errorf("unknown sort field %q", field.Field)
+		}
+		if field.Desc {
+			orderBy = append(orderBy, field.Field+" DESC")
+		} else {
+			orderBy = append(orderBy, field.Field)
+		}
+	}
+	orderBy = append(orderBy, "id")
+
+	query := fmt.Sprintf("SELECT * FROM people WHERE %s ORDER BY %s", strings.Join(conditions, " AND "), strings.Join(orderBy, ", "))
+	return query, args, nil
+}
+
+// GetPersonById возвращает информацию о неудаленном человеке по его идентификатору.
+func (r *Repository) GetPersonById(ctx context.Context, id int) (*model.Person, error) {
 	var person model.Person
-	err := r.db.Get(&person, "SELECT * FROM people WHERE id = $1", id)
+	err := r.read(ctx, func(db sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, db, &person, "SELECT * FROM people WHERE id = $1 AND deleted_at IS NULL", id)
+	})
 	if err != nil {
 		helpers.LogAndReturnError(r.logger, "error when querying the database:", err)
 		return nil, err
@@ -80,42 +143,205 @@ func (r *Repository) GetPersonById(id int) (*model.Person, error) {
 	return &person, nil
 }
 
-// UpdatePerson обновляет информацию о человеке в базе данных.
-func (r *Repository) UpdatePerson(person *model.Person) error {
+// UpdatePerson обновляет информацию о человеке в базе данных, увеличивает его версию и записывает изменение в историю.
+// Если person.Version не равна нулю, обновление выполняется только при совпадении версии,
+// иначе возвращается ErrVersionMismatch. После обновления person.Version содержит новую версию.
+func (r *Repository) UpdatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error {
 	query := `UPDATE people SET name=:name, surname=:surname, patronymic=:patronymic, 
-	age=:age, gender=:gender, nationality=:nationality WHERE id=:id`
+	age=:age, gender=:gender, nationality=:nationality, version=version+1, updated_at=:updated_at
+	WHERE id=:id
+	RETURNING *`
 
-	result, err := r.db.NamedExec(query, person)
-	if err != nil {
-		return ErrNamedExec
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		before, err := r.lockPerson(ctx, tx, int(person.ID), false)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(before, person.Version); err != nil {
+			return err
+		}
+
+		person.UpdatedAt = r.now()
+		rows, err := sqlx.NamedQueryContext(ctx, tx, query, person)
+		if err != nil {
+			return ErrNamedExec
+		}
+		defer rows.Close()
+
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return sql.ErrNoRows
+		}
+		var after model.Person
+		if err := rows.StructScan(&after); err != nil {
+			return err
+		}
+		rows.Close()
+
+		person.Version, person.CreatedAt, person.EnrichedAt = after.Version, after.CreatedAt, after.EnrichedAt
+		return r.recordChange(ctx, tx, model.OperationUpdate, audit, before, &after)
+	})
+}
+
+// personColumns колонки people, доступные для фильтрации и частичного обновления.
+var personColumns = map[string]bool{
+	"name":        true,
+	"surname":     true,
+	"patronymic":  true,
+	"age":         true,
+	"gender":      true,
+	"nationality": true,
+}
+
+// PatchPerson обновляет у человека только переданные колонки fields, записывает изменение в историю
+// и возвращает его новую версию. Возвращает sql.ErrNoRows, если человека с таким id нет, и ErrVersionMismatch,
+// если version не равна нулю и не совпадает с текущей версией.
+func (r *Repository) PatchPerson(ctx context.Context, id, version int, fields map[string]interface{}, audit model.AuditInfo) (int, error) {
+	r.logger.Debug("Repository: Handling PatchPerson request")
+
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		if !personColumns[column] {
+			return 0, fmt.Errorf("column %q cannot be patched", column)
+		}
+		columns = append(columns, column)
 	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("%w: %v", ErrRowsAffected, err)
+	if len(columns) == 0 {
+		return version, nil
 	}
+	sort.Strings(columns)
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	assignments := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns)+2)
+	for i, column := range columns {
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, i+1))
+		args = append(args, fields[column])
 	}
-	return nil
+	args = append(args, r.now(), id)
+
+	query := fmt.Sprintf("UPDATE people SET %s, version = version + 1, updated_at = $%d WHERE id = $%d RETURNING *",
+		strings.Join(assignments, ", "), len(args)-1, len(args))
+
+	var after model.Person
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		before, err := r.lockPerson(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(before, version); err != nil {
+			return err
+		}
+
+		if err := tx.QueryRowxContext(ctx, query, args...).StructScan(&after); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, model.OperationUpdate, audit, before, &after)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return after.Version, nil
 }
 
-// DeletePerson удаляет запись о человеке из базы данных по его id.
-func (r *Repository) DeletePerson(id int) error {
-	result, err := r.db.Exec("DELETE FROM people WHERE id = $1", id)
+// DeletePerson помечает запись о человеке удаленной по его id и записывает удаление в историю,
+// запись можно восстановить через RestorePerson.
+// Если version не равна нулю, запись удаляется только при совпадении версии, иначе возвращается ErrVersionMismatch.
+func (r *Repository) DeletePerson(ctx context.Context, id, version int, audit model.AuditInfo) error {
+	query := `UPDATE people SET deleted_at = $2, updated_at = $2, version = version + 1 WHERE id = $1 RETURNING *`
+
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		before, err := r.lockPerson(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(before, version); err != nil {
+			return err
+		}
+
+		var after model.Person
+		if err := tx.QueryRowxContext(ctx, query, id, r.now()).StructScan(&after); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, model.OperationDelete, audit, before, &after)
+	})
+}
+
+// RestorePerson восстанавливает удаленную запись о человеке, записывает восстановление в историю и возвращает запись.
+// Возвращает sql.ErrNoRows, если удаленного человека с таким id нет.
+func (r *Repository) RestorePerson(ctx context.Context, id int, audit model.AuditInfo) (*model.Person, error) {
+	query := `UPDATE people SET deleted_at = NULL, updated_at = $2, version = version + 1 WHERE id = $1 RETURNING *`
+
+	var after model.Person
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		before, err := r.lockPerson(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.QueryRowxContext(ctx, query, id, r.now()).StructScan(&after); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, model.OperationRestore, audit, before, &after)
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &after, nil
+}
 
-	rowsAffected, err := result.RowsAffected()
+// PurgeDeletedPeople окончательно удаляет записи, помеченные удаленными раньше deletedBefore,
+// и записывает удаление каждой из них в историю. Возвращает количество удаленных записей.
+func (r *Repository) PurgeDeletedPeople(ctx context.Context, deletedBefore time.Time, audit model.AuditInfo) (int64, error) {
+	var purged []model.Person
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		query := `DELETE FROM people WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING *`
+		if err := tx.SelectContext(ctx, &purged, query, deletedBefore.UTC()); err != nil {
+			return err
+		}
+
+		for i := range purged {
+			if err := r.recordChange(ctx, tx, model.OperationPurge, audit, &purged[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrRowsAffected, err)
+		return 0, err
+	}
+	return int64(len(purged)), nil
+}
 
+// lockPerson блокирует до конца транзакции tx запись о человеке и возвращает ее текущее состояние.
+// При deleted ищется удаленная запись, иначе неудаленная. Возвращает sql.ErrNoRows, если записи нет.
+func (r *Repository) lockPerson(ctx context.Context, tx *sqlx.Tx, id int, deleted bool) (*model.Person, error) {
+	query := "SELECT * FROM people WHERE id = $1 AND deleted_at IS NULL"
+	if deleted {
+		query = "SELECT * FROM people WHERE id = $1 AND deleted_at IS NOT NULL"
+	}
+	// В SQLite нет блокировки строк, транзакции записи и так выполняются по одной.
+	if r.driver != driverSQLite {
+		query += " FOR UPDATE"
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	var person model.Person
+	if err := tx.GetContext(ctx, &person, query, id); err != nil {
+		return nil, err
 	}
+	return &person, nil
+}
 
+// now возвращает текущее время в UTC: SQLite сравнивает время как строки, поэтому часовой пояс должен быть один.
+func (r *Repository) now() time.Time {
+	return time.Now().UTC()
+}
+
+// checkVersion возвращает ErrVersionMismatch, если version не равна нулю и отличается от версии person.
+func checkVersion(person *model.Person, version int) error {
+	if version != 0 && person.Version != version {
+		return ErrVersionMismatch
+	}
 	return nil
 }
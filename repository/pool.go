@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"testProject/pkg/logging"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	connectInitialBackoff = 500 * time.Millisecond
+	connectMaxBackoff     = 10 * time.Second
+)
+
+// PoolOptions настройки пула соединений. Нулевые значения оставляют настройки database/sql по умолчанию.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// apply применяет настройки к пулу db.
+func (o PoolOptions) apply(db *sqlx.DB) {
+	if o.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(o.MaxOpenConns)
+	}
+	if o.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(o.MaxIdleConns)
+	}
+	if o.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(o.ConnMaxLifetime)
+	}
+}
+
+// connectWithRetry подключается к базе данных, повторяя попытки с удваивающейся паузой, пока не отменен ctx.
+// Так приложение дожидается базы данных, которая запускается или перезапускается одновременно с ним.
+func connectWithRetry(ctx context.Context, driverName, dataSourceName string, logger *logging.Logger) (*sqlx.DB, error) {
+	backoff := connectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		db, err := sqlx.ConnectContext(ctx, driverName, dataSourceName)
+		if err == nil {
+			return db, nil
+		}
+		logger.Warnf("Failed to connect to the database, attempt %d, retrying in %s: %v", attempt, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > connectMaxBackoff {
+			backoff = connectMaxBackoff
+		}
+	}
+}
+
+// Ping проверяет, что база данных доступна.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Close закрывает соединения с основной базой данных и репликами.
+func (r *Repository) Close() error {
+	var errs []error
+	if r.replicas != nil {
+		for _, db := range r.replicas.dbs {
+			errs = append(errs, db.Close())
+		}
+	}
+	errs = append(errs, r.db.Close())
+	return errors.Join(errs...)
+}
+
+// Stats возвращает статистику пулов соединений: основной базы данных по ключу "primary"
+// и реплик по ключам "replica_N", где N - номер реплики в db.replicas.
+func (r *Repository) Stats() map[string]sql.DBStats {
+	stats := map[string]sql.DBStats{"primary": r.db.Stats()}
+	if r.replicas != nil {
+		for i, db := range r.replicas.dbs {
+			stats[fmt.Sprintf("replica_%d", i)] = db.Stats()
+		}
+	}
+	return stats
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type consistencyKey struct{}
+
+// WithStrongConsistency помечает ctx так, что чтения в нем выполняются на основной базе данных,
+// а не на репликах, которые могут отставать от нее.
+func WithStrongConsistency(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, true)
+}
+
+// isStrongConsistency сообщает, что ctx требует чтения с основной базы данных.
+func isStrongConsistency(ctx context.Context) bool {
+	strong, _ := ctx.Value(consistencyKey{}).(bool)
+	return strong
+}
+
+// replicaSet реплики для чтения, которые выбираются по кругу среди доступных.
+type replicaSet struct {
+	dbs     []*sqlx.DB
+	healthy []atomic.Bool
+	next    atomic.Uint32
+}
+
+// AddReplicas подключает реплики для чтения по dsns с настройками пула pool.
+// Недоступная при подключении реплика не используется, пока ее не вернет CheckReplicas.
+func (r *Repository) AddReplicas(ctx context.Context, dsns []string, pool PoolOptions) error {
+	replicas := &replicaSet{
+		dbs:     make([]*sqlx.DB, 0, len(dsns)),
+		healthy: make([]atomic.Bool, len(dsns)),
+	}
+	for _, dsn := range dsns {
+		db, err := sqlx.Open(r.driver, dsn)
+		if err != nil {
+			for _, opened := range replicas.dbs {
+				opened.Close()
+			}
+			return fmt.Errorf("failed to open the replica: %v", err)
+		}
+		pool.apply(db)
+		replicas.dbs = append(replicas.dbs, db)
+	}
+
+	r.replicas = replicas
+	r.CheckReplicas(ctx)
+	return nil
+}
+
+// CheckReplicas проверяет доступность реплик: недоступные исключаются из чтения, восстановившиеся возвращаются.
+func (r *Repository) CheckReplicas(ctx context.Context) {
+	if r.replicas == nil {
+		return
+	}
+	for i, db := range r.replicas.dbs {
+		err := db.PingContext(ctx)
+		if wasHealthy := r.replicas.healthy[i].Swap(err == nil); wasHealthy != (err == nil) {
+			if err != nil {
+				r.logger.Warnf("Replica %d is unavailable, reading from other databases: %v", i, err)
+			} else {
+				r.logger.Infof("Replica %d is available", i)
+			}
+		}
+	}
+}
+
+// read выполняет fn на соединении для чтения из reader. Если реплика вернула ошибку соединения,
+// она исключается из чтения до следующей проверки CheckReplicas, а fn выполняется еще раз на основной базе данных.
+// Отмена запроса сервером возвращается как истечение времени, см. timeoutError.
+func (r *Repository) read(ctx context.Context, fn func(db sqlx.ExtContext) error) error {
+	db, replica := r.reader(ctx)
+	err := fn(db)
+	if replica < 0 || !isConnectionError(err) || ctx.Err() != nil {
+		return timeoutError(err)
+	}
+	if r.replicas.healthy[replica].Swap(false) {
+		r.logger.Warnf("Replica %d is unavailable, reading from other databases: %v", replica, err)
+	}
+	return timeoutError(fn(r.db))
+}
+
+// reader возвращает соединение для чтения и номер реплики или -1: открытую транзакцию, следующую доступную
+// реплику или основную базу данных, если реплик нет, все недоступны или ctx требует строгой согласованности.
+func (r *Repository) reader(ctx context.Context) (sqlx.ExtContext, int) {
+	if r.tx != nil || r.replicas == nil || isStrongConsistency(ctx) {
+		return r.conn(), -1
+	}
+
+	n := uint32(len(r.replicas.dbs))
+	for i := uint32(0); i < n; i++ {
+		index := r.replicas.next.Add(1) % n
+		if r.replicas.healthy[index].Load() {
+			return r.replicas.dbs[index], int(index)
+		}
+	}
+	return r.db, -1
+}
+
+// isConnectionError сообщает, что err - ошибка соединения с базой данных, а не самого запроса.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Класс 08 - ошибки соединения, 57P01-57P03 - сервер останавливается или еще не принимает соединения.
+		return pqErr.Code.Class() == "08" || pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_CANTOPEN, sqlite3.SQLITE_IOERR:
+			return true
+		}
+	}
+	return false
+}
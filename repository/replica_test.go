@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"testProject/internal/model"
+)
+
+func TestReplicaRouting(t *testing.T) {
+	ctx := context.Background()
+	primary := newSQLiteRepository(t)
+	replica := newSQLiteRepository(t)
+	audit := model.AuditInfo{Actor: "tester"}
+
+	if err := primary.CreatePerson(ctx, &model.Person{Name: "Primary", Surname: "Person"}, audit); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := replica.CreatePerson(ctx, &model.Person{Name: "Replica", Surname: "Person"}, audit); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	replicaPath := filepath.Join(t.TempDir(), "replica.db")
+	if _, err := replica.db.ExecContext(ctx, "VACUUM INTO $1", replicaPath); err != nil {
+		t.Fatalf("Failed to copy replica: %v", err)
+	}
+	if err := primary.AddReplicas(ctx, []string{fmt.Sprintf("file:%s?mode=ro", replicaPath)}, PoolOptions{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	t.Cleanup(func() { primary.replicas.dbs[0].Close() })
+
+	name := func(ctx context.Context) string {
+		person, err := primary.GetPersonById(ctx, 1)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		return person.Name
+	}
+
+	if got := name(ctx); got != "Replica" {
+		t.Errorf("Expected read from replica, got %s", got)
+	}
+	if got := name(WithStrongConsistency(ctx)); got != "Primary" {
+		t.Errorf("Expected strong read from primary, got %s", got)
+	}
+
+	primary.replicas.dbs[0].Close()
+	primary.CheckReplicas(ctx)
+	if got := name(ctx); got != "Primary" {
+		t.Errorf("Expected fallback to primary for unavailable replica, got %s", got)
+	}
+}
+
+func TestReplicaFailover(t *testing.T) {
+	ctx := context.Background()
+	primary := newSQLiteRepository(t)
+	if err := primary.CreatePerson(ctx, &model.Person{Name: "Primary", Surname: "Person"}, model.AuditInfo{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	// Файла реплики нет, но проверка еще не заметила этого: реплика считается доступной.
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	if err := primary.AddReplicas(ctx, []string{fmt.Sprintf("file:%s?mode=ro", missing)}, PoolOptions{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	t.Cleanup(func() { primary.replicas.dbs[0].Close() })
+	primary.replicas.healthy[0].Store(true)
+
+	person, err := primary.GetPersonById(ctx, 1)
+	if err != nil || person.Name != "Primary" {
+		t.Fatalf("Expected read to fail over to primary, got %+v and %v", person, err)
+	}
+	if primary.replicas.healthy[0].Load() {
+		t.Error("Expected failed replica to be marked unavailable")
+	}
+
+	primary.replicas.healthy[0].Store(true)
+	var exported []string
+	err = primary.ExportPeople(ctx, model.PersonFilter{}, func(person *model.Person) error {
+		exported = append(exported, person.Name)
+		return nil
+	})
+	if err != nil || len(exported) != 1 || exported[0] != "Primary" {
+		t.Errorf("Expected export to fail over to primary, got %v and %v", exported, err)
+	}
+}
+
+func TestCloseReplicas(t *testing.T) {
+	ctx := context.Background()
+	primary := newSQLiteRepository(t)
+	replicaPath := filepath.Join(t.TempDir(), "replica.db")
+	if err := primary.AddReplicas(ctx, []string{replicaPath}, PoolOptions{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if stats := primary.Stats(); len(stats) != 2 {
+		t.Errorf("Expected statistics of primary and replica pools, got %v", stats)
+	}
+
+	if err := primary.Close(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := primary.replicas.dbs[0].PingContext(ctx); err == nil {
+		t.Error("Expected replica connection to be closed")
+	}
+	if err := primary.Ping(ctx); err == nil {
+		t.Error("Expected primary connection to be closed")
+	}
+}
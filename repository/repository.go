@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"testProject/internal/model"
+)
+
+// PersonRepository хранилище людей и истории их изменений.
+// Реализации: Repository для Postgres и SQLite и MemoryRepository для хранения в памяти.
+type PersonRepository interface {
+	CreatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error
+	BulkCreatePeople(ctx context.Context, people []model.Person, audit model.AuditInfo) (*model.BulkCreateResult, error)
+	GetPeople(ctx context.Context, filter model.PersonFilter) ([]model.Person, error)
+	ExportPeople(ctx context.Context, filter model.PersonFilter, fn func(person *model.Person) error) error
+	GetPersonById(ctx context.Context, id int) (*model.Person, error)
+	UpdatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error
+	PatchPerson(ctx context.Context, id, version int, fields map[string]interface{}, audit model.AuditInfo) (int, error)
+	DeletePerson(ctx context.Context, id, version int, audit model.AuditInfo) error
+	RestorePerson(ctx context.Context, id int, audit model.AuditInfo) (*model.Person, error)
+	PurgeDeletedPeople(ctx context.Context, deletedBefore time.Time, audit model.AuditInfo) (int64, error)
+	GetPersonHistory(ctx context.Context, personID int) ([]model.PersonChange, error)
+	GetPersonChangeAsOf(ctx context.Context, personID int, asOf time.Time) (*model.PersonChange, error)
+}
+
+// IdempotencyRepository хранилище ответов на запросы с ключом идемпотентности.
+type IdempotencyRepository interface {
+	ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (*model.IdempotencyRecord, bool, error)
+	SaveIdempotencyResponse(ctx context.Context, key string, statusCode int, contentType string, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+// Storage все хранилища, которые нужны приложению.
+type Storage interface {
+	PersonRepository
+	IdempotencyRepository
+
+	Transactor
+
+	// Ping проверяет, что хранилище доступно.
+	Ping(ctx context.Context) error
+
+	// Close закрывает соединения хранилища, после него хранилище использовать нельзя.
+	Close() error
+}
+
+// Transactor хранилище, которое выполняет несколько операций одной транзакцией.
+type Transactor interface {
+	// WithTx выполняет fn в одной транзакции: изменения через repo применяются, только если fn вернула nil.
+	// При конфликте с параллельными изменениями fn может выполняться повторно.
+	WithTx(ctx context.Context, fn func(repo Storage) error) error
+}
+
+var (
+	_ Storage = (*Repository)(nil)
+	_ Storage = (*MemoryRepository)(nil)
+)
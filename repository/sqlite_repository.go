@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"fmt"
+
+	"testProject/pkg/logging"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	driverPostgres = "postgres"
+	driverSQLite   = "sqlite"
+)
+
+func init() {
+	sqlx.BindDriver(driverSQLite, sqlx.QUESTION)
+}
+
+// NewSQLiteRepository создает repository поверх файла SQLite по пути path. Миграции применяет Migrator.
+// Предназначен для локального запуска и демонстрационных стендов без сервера Postgres.
+func NewSQLiteRepository(path string, logger *logging.Logger) (*Repository, error) {
+	dsn := fmt.Sprintf("file:%s?_time_format=sqlite&_txlock=immediate&_pragma=busy_timeout(5000)", path)
+
+	db, err := sqlx.Connect(driverSQLite, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the database: %v", err)
+	}
+	// SQLite выполняет записи по одной, одно соединение исключает ошибки SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	return &Repository{db: db, driver: driverSQLite, txOptions: DefaultTxOptions, logger: logger}, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// serializationFailure код ошибки Postgres, с которым завершается конфликтующая транзакция.
+const serializationFailure = "40001"
+
+// queryCanceled код ошибки Postgres, с которым запрос отменяется, например по statement_timeout.
+const queryCanceled = "57014"
+
+// ErrTxConflict транзакция не выполнена из-за параллельных изменений за все попытки.
+var ErrTxConflict = errors.New("transaction conflicts with concurrent changes")
+
+// TxOptions настройки транзакций Repository.
+type TxOptions struct {
+	Isolation  sql.IsolationLevel // уровень изоляции, sql.LevelDefault оставляет уровень базы данных
+	MaxRetries int                // количество попыток выполнить транзакцию при ошибке сериализации
+}
+
+// DefaultTxOptions настройки транзакций, с которыми создается Repository.
+var DefaultTxOptions = TxOptions{Isolation: sql.LevelDefault, MaxRetries: 3}
+
+// ParseIsolationLevel возвращает уровень изоляции по названию из конфигурации,
+// например "read committed" или "serializable". Пустая строка означает уровень базы данных.
+func ParseIsolationLevel(name string) (sql.IsolationLevel, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "default" {
+		return sql.LevelDefault, nil
+	}
+	for _, level := range []sql.IsolationLevel{sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelRepeatableRead, sql.LevelSerializable} {
+		if strings.ToLower(level.String()) == name {
+			return level, nil
+		}
+	}
+	return sql.LevelDefault, fmt.Errorf("unknown isolation level %q", name)
+}
+
+// SetTxOptions задает уровень изоляции и количество попыток для транзакций repository.
+func (r *Repository) SetTxOptions(opts TxOptions) {
+	r.txOptions = opts
+}
+
+// WithTx выполняет fn с репозиторием, все запросы которого идут в одной транзакции.
+// Транзакция фиксируется, если fn вернула nil, и откатывается при ошибке.
+// При ошибке сериализации fn выполняется заново в новой транзакции, поэтому не должна иметь других побочных эффектов.
+// WithTx внутри fn использует уже открытую транзакцию.
+func (r *Repository) WithTx(ctx context.Context, fn func(repo Storage) error) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		txRepo := *r
+		txRepo.tx = tx
+		return fn(&txRepo)
+	})
+}
+
+// conn возвращает открытую транзакцию или пул соединений, если транзакции нет.
+func (r *Repository) conn() sqlx.ExtContext {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
+}
+
+// inTx выполняет fn в транзакции: фиксирует ее при успехе и откатывает при ошибке.
+// Если repository уже работает в транзакции, fn выполняется в ней, фиксирует ее внешний WithTx.
+// Отмена запроса сервером возвращается как истечение времени, см. timeoutError.
+func (r *Repository) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := r.runTx(ctx, fn)
+		if err == nil || !isSerializationFailure(err) {
+			return timeoutError(err)
+		}
+		if attempt >= r.txOptions.MaxRetries {
+			return fmt.Errorf("%w: %w", ErrTxConflict, err)
+		}
+		r.logger.Warnf("Retrying transaction after serialization failure, attempt %d: %v", attempt, err)
+
+		// Пауза растет с каждой попыткой, чтобы конфликтующие транзакции успели завершиться.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+}
+
+// runTx выполняет одну попытку транзакции.
+func (r *Repository) runTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{Isolation: r.txOptions.Isolation})
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.logger.Errorf("Failed to rollback transaction: %v", rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// timeoutError оборачивает в context.DeadlineExceeded отмену запроса сервером Postgres, чтобы она обрабатывалась
+// как истечение времени запроса, а не как внутренняя ошибка. Остальные ошибки возвращаются как есть.
+func timeoutError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == queryCanceled && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return err
+}
+
+// isSerializationFailure сообщает, что транзакция отменена из-за конфликта с параллельной и ее можно повторить.
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == serializationFailure
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestTimeoutError(t *testing.T) {
+	canceled := &pq.Error{Code: queryCanceled, Message: "canceling statement due to statement timeout"}
+
+	err := timeoutError(canceled)
+	var pqErr *pq.Error
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &pqErr) {
+		t.Fatalf("Expected canceled query to wrap context.DeadlineExceeded and the driver error, got %v", err)
+	}
+	if again := timeoutError(err); again != err {
+		t.Errorf("Expected timeout error to be wrapped once, got %v", again)
+	}
+
+	conflict := &pq.Error{Code: serializationFailure}
+	if err := timeoutError(conflict); err != conflict {
+		t.Errorf("Expected other errors to be returned as is, got %v", err)
+	}
+	if err := timeoutError(nil); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+)
+
+// adminKey ключ контекста с признаком администратора.
+type adminKey struct{}
+
+// WithAdmin отмечает, что запрос в ctx выполняет администратор. Без этого сервис не показывает удаленные записи
+// и не восстанавливает их.
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin сообщает, выполняет ли запрос в ctx администратор.
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// AuthorizeAdmin возвращает ctx администратора, если authorization - учетные данные вида "Bearer <токен>"
+// с токеном token. Без учетных данных или с неверными ctx возвращается как есть: операции администратора
+// тогда завершаются ErrForbidden. Пустой token отключает доступ администратора.
+func AuthorizeAdmin(ctx context.Context, authorization, token string) context.Context {
+	credentials, found := strings.CutPrefix(authorization, "Bearer ")
+	if !found || token == "" || subtle.ConstantTimeCompare([]byte(credentials), []byte(token)) != 1 {
+		return ctx
+	}
+	return WithAdmin(ctx)
+}
+
+// requireAdmin возвращает ErrForbidden, если запрос в ctx выполняет не администратор.
+func (s *Service) requireAdmin(ctx context.Context, action string) error {
+	if IsAdmin(ctx) {
+		return nil
+	}
+	s.logger.Warnf("Forbidden to %s without admin access", action)
+	return ErrForbidden
+}
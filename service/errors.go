@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"testProject/repository"
+)
+
+// Ошибки сервиса. Методы Service оборачивают их через %w, проверять ошибки нужно с помощью errors.Is.
+var (
+	// ErrNotFound запрошенная запись не существует.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict изменение конфликтует с параллельными изменениями, его можно повторить.
+	ErrConflict = errors.New("conflict with concurrent changes")
+	// ErrForbidden операция доступна только администратору, см. WithAdmin.
+	ErrForbidden = errors.New("admin access required")
+	// ErrValidation данные запроса некорректны.
+	ErrValidation = errors.New("validation failed")
+	// ErrUpstreamUnavailable внешний сервис обогащения недоступен или вернул некорректный ответ.
+	ErrUpstreamUnavailable = errors.New("upstream service unavailable")
+)
+
+// storageError переводит ошибку хранилища в ошибку сервиса: отсутствие записи subject - в ErrNotFound,
+// несовпадение версии - в ErrVersionMismatch, конфликт транзакций - в ErrConflict.
+// Остальные ошибки оборачиваются с описанием действия action, отмена ctx возвращается как есть.
+// Ошибки не логируются: их логирует транспорт вместе со статусом ответа.
+func storageError(err error, subject, action string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("%s %w", subject, ErrNotFound)
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return ErrVersionMismatch
+	case errors.Is(err, repository.ErrTxConflict):
+		return fmt.Errorf("failed to %s: %w", action, ErrConflict)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// upstreamError оборачивает ошибку сервиса обогащения name в ErrUpstreamUnavailable.
+// Отмена и истечение ctx возвращаются как есть: запрос прерван не по вине внешнего сервиса.
+func upstreamError(name string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %s: %v", ErrUpstreamUnavailable, name, err)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"testProject/internal/model"
+)
+
+// eventBuffer сколько событий подписчик может не прочитать, прежде чем подписка будет закрыта.
+const eventBuffer = 64
+
+// PersonEvent изменение человека, выполненное через сервис. Operation - одна из операций истории изменений
+// model.OperationCreate, OperationUpdate, OperationDelete или OperationRestore. Для удаления Person содержит только ID.
+type PersonEvent struct {
+	Operation string
+	Person    model.Person
+}
+
+// personEvents рассылает события изменений подписчикам.
+type personEvents struct {
+	mu          sync.Mutex
+	subscribers map[chan PersonEvent]struct{}
+}
+
+// Subscribe возвращает канал событий изменений людей, выполненных этим экземпляром сервиса после подписки.
+// Импорт и окончательное удаление событий не создают. Канал закрывается после отмены ctx или раньше,
+// если подписчик не успевает читать события; тогда ctx.Err() равна nil.
+func (s *Service) Subscribe(ctx context.Context) <-chan PersonEvent {
+	events := make(chan PersonEvent, eventBuffer)
+	s.events.mu.Lock()
+	s.events.subscribers[events] = struct{}{}
+	s.events.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.events.mu.Lock()
+		defer s.events.mu.Unlock()
+		if _, ok := s.events.subscribers[events]; ok {
+			delete(s.events.subscribers, events)
+			close(events)
+		}
+	}()
+	return events
+}
+
+// publish отправляет событие всем подписчикам, не дожидаясь их.
+func (s *Service) publish(operation string, person model.Person) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	for events := range s.events.subscribers {
+		select {
+		case events <- PersonEvent{Operation: operation, Person: person}:
+		default:
+			s.logger.Warnf("Closing person events subscription: subscriber is too slow")
+			delete(s.events.subscribers, events)
+			close(events)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"testProject/internal/importer"
+	"testProject/internal/model"
+)
+
+// ErrImportJobNotFound фоновый импорт с таким идентификатором не найден или уже удален.
+var ErrImportJobNotFound = fmt.Errorf("import job %w", ErrNotFound)
+
+const (
+	defaultImportBatchSize = 1000
+	// importJobTTL время, в течение которого хранится завершенный фоновый импорт.
+	importJobTTL = 24 * time.Hour
+)
+
+// ImportOptions параметры импорта людей.
+// BatchSize строк создаются одной транзакцией, Enrich заполняет пустые возраст, пол и национальность
+// через внешние сервисы, как при создании одного человека.
+type ImportOptions struct {
+	BatchSize int
+	Enrich    bool
+}
+
+// importJobs фоновые импорты, которые хранятся в памяти процесса.
+type importJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*model.ImportJob
+}
+
+// Import читает людей из reader и создает их пачками по opts.BatchSize строк.
+// Некорректные строки пропускаются и попадают в отчет. progress, если задан, вызывается с отчетом после каждой пачки.
+// Ошибка возвращается, если чтение или запись прерваны, отчет при этом содержит уже загруженные строки.
+func (s *Service) Import(ctx context.Context, reader importer.Reader, opts ImportOptions, audit model.AuditInfo, progress func(report model.ImportReport)) (*model.ImportReport, error) {
+	s.logger.Debug("Service: Handling Import request")
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	report := &model.ImportReport{Errors: []model.BulkRowError{}}
+	batch := make([]model.Person, 0, batchSize)
+	rows := make([]int, 0, batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := s.ImportPeople(ctx, batch, audit)
+		if err != nil {
+			return err
+		}
+		for _, rowErr := range result.Errors {
+			report.Reject(rows[rowErr.Row], rowErr.Error)
+		}
+		report.Accepted += result.Created()
+		batch, rows = batch[:0], rows[:0]
+
+		if progress != nil {
+			progress(*report)
+		}
+		return nil
+	}
+
+	for row := 1; ; row++ {
+		// Без обогащения строки не обращаются к ctx до записи пачки, поэтому отмена проверяется на каждой строке.
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		person, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, importer.ErrInvalidRow) {
+			report.Reject(row, err.Error())
+			continue
+		}
+		if err != nil {
+			// Файл поврежден дальше, чем одна строка, например незакрытые кавычки CSV: это ошибка данных клиента.
+			// Ошибка чтения оборачивается, чтобы обработчик мог отличить, например, превышение размера загрузки.
+			return report, fmt.Errorf("%w: failed to read row %d: %w", ErrValidation, row, err)
+		}
+
+		if opts.Enrich && person.Validate() == nil {
+			if err := s.enrichMissing(ctx, &person); err != nil {
+				if ctx.Err() != nil {
+					return report, ctx.Err()
+				}
+				report.Reject(row, fmt.Sprintf("failed to enrich: %v", err))
+				continue
+			}
+		}
+
+		batch, rows = append(batch, person), append(rows, row)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, flush()
+}
+
+// enrichMissing заполняет пустые возраст, пол и национальность через внешние сервисы
+// и отмечает время обогащения, если хотя бы одно поле было пустым.
+func (s *Service) enrichMissing(ctx context.Context, person *model.Person) error {
+	if person.Age != 0 && person.Gender != "" && person.Nationality != "" {
+		return nil
+	}
+	enrichedAt := time.Now().UTC()
+	person.EnrichedAt = &enrichedAt
+
+	var err error
+	if person.Age == 0 {
+		if person.Age, err = s.enrichWithAge(ctx, person.Name); err != nil {
+			return err
+		}
+	}
+	if person.Gender == "" {
+		if person.Gender, err = s.enrichWithGender(ctx, person.Name); err != nil {
+			return err
+		}
+	}
+	if person.Nationality == "" {
+		if person.Nationality, err = s.enrichWithNationality(ctx, person.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartImportJob запускает Import в фоне и возвращает задачу, состояние которой доступно через GetImportJob.
+// Импорт не зависит от контекста запроса и прерывается отменой фонового контекста сервиса;
+// cleanup вызывается по его завершении, например для удаления временного файла.
+func (s *Service) StartImportJob(reader importer.Reader, opts ImportOptions, audit model.AuditInfo, cleanup func()) (*model.ImportJob, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	job := &model.ImportJob{
+		ID:        id,
+		Status:    model.ImportJobRunning,
+		Report:    model.ImportReport{Errors: []model.BulkRowError{}},
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.imports.mu.Lock()
+	s.imports.removeExpired()
+	s.imports.jobs[id] = job
+	started := *job
+	s.imports.mu.Unlock()
+
+	go func() {
+		if cleanup != nil {
+			defer cleanup()
+		}
+
+		report, err := s.Import(s.background, reader, opts, audit, func(report model.ImportReport) {
+			report.Errors = append([]model.BulkRowError(nil), report.Errors...)
+			s.imports.mu.Lock()
+			job.Report = report
+			s.imports.mu.Unlock()
+		})
+
+		s.imports.mu.Lock()
+		defer s.imports.mu.Unlock()
+		finished := time.Now().UTC()
+		job.Report, job.FinishedAt, job.Status = *report, &finished, model.ImportJobCompleted
+		if err != nil {
+			s.logger.Errorf("Import job %s failed: %v", id, err)
+			job.Status, job.Error = model.ImportJobFailed, err.Error()
+		}
+	}()
+	return &started, nil
+}
+
+// GetImportJob возвращает состояние фонового импорта.
+func (s *Service) GetImportJob(id string) (*model.ImportJob, error) {
+	s.imports.mu.Lock()
+	defer s.imports.mu.Unlock()
+
+	job, ok := s.imports.jobs[id]
+	if !ok {
+		return nil, ErrImportJobNotFound
+	}
+	copied := *job
+	copied.Report.Errors = append([]model.BulkRowError(nil), job.Report.Errors...)
+	return &copied, nil
+}
+
+// removeExpired удаляет импорты, завершенные раньше importJobTTL назад. Вызывается под mu.
+func (j *importJobs) removeExpired() {
+	for id, job := range j.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > importJobTTL {
+			delete(j.jobs, id)
+		}
+	}
+}
+
+// newJobID генерирует случайный идентификатор фоновой задачи.
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
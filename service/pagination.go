@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"testProject/internal/model"
+)
+
+// PeoplePage страница списка людей.
+type PeoplePage struct {
+	People []model.Person
+	// HasNext сообщает, есть ли в списке записи после страницы.
+	HasNext bool
+}
+
+// GetPeoplePage возвращает страницу списка людей: не больше filter.Limit записей начиная со смещения filter.Offset.
+// Права доступа к удаленным записям те же, что и в GetPeople.
+func (s *Service) GetPeoplePage(ctx context.Context, filter model.PersonFilter) (*PeoplePage, error) {
+	// Лишняя запись показывает, есть ли следующая страница.
+	lookahead := filter
+	lookahead.Limit++
+	people, err := s.GetPeople(ctx, lookahead)
+	if err != nil {
+		return nil, err
+	}
+	page := &PeoplePage{People: people, HasNext: len(people) > filter.Limit}
+	if page.HasNext {
+		page.People = people[:filter.Limit]
+	}
+	return page, nil
+}
+
+// cursorPrefix префикс курсора перед смещением записи.
+const cursorPrefix = "offset:"
+
+// EncodeCursor возвращает непрозрачный для клиента курсор записи со смещением offset в списке людей.
+// Курсор хранит только смещение, поэтому страницы сдвигаются, если перед ними добавляются записи.
+func EncodeCursor(offset int) string {
+	return base64.URLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// DecodeCursor возвращает смещение записи из курсора EncodeCursor или ErrValidation, если курсор некорректен.
+func DecodeCursor(cursor string) (int, error) {
+	data, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid cursor %q", ErrValidation, cursor)
+	}
+	value, ok := strings.CutPrefix(string(data), cursorPrefix)
+	offset, err := strconv.Atoi(value)
+	if !ok || err != nil || offset < 0 {
+		return 0, fmt.Errorf("%w: invalid cursor %q", ErrValidation, cursor)
+	}
+	return offset, nil
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -14,150 +15,414 @@ import (
 	"testProject/repository"
 )
 
+// ErrVersionMismatch версия человека не совпадает с ожидаемой клиентом.
+var ErrVersionMismatch = errors.New("person was modified by another request")
+
+// SystemActor автор изменений, которые приложение выполняет само, без запроса клиента.
+const SystemActor = "system"
+
 // Service представляет собой сервис для работы с данными о людях.
 type Service struct {
-	repo   *repository.Repository
-	logger *logging.Logger
+	repo    repository.PersonRepository
+	logger  *logging.Logger
+	imports *importJobs
+	events  *personEvents
+	// background контекст фоновых задач сервиса, например импорта.
+	background context.Context
+
+	// Адреса внешних сервисов обогащения, в тестах заменяются на локальный сервер.
+	agifyURL       string
+	genderizeURL   string
+	nationalizeURL string
+}
+
+// WithStrongConsistency требует, чтобы чтения в ctx возвращали актуальные данные основной базы данных, а не реплик.
+func WithStrongConsistency(ctx context.Context) context.Context {
+	return repository.WithStrongConsistency(ctx)
 }
 
 // NewService создает новый экземпляр сервиса с переданным репозиторием и логгером в конструкторе.
-func NewService(repo *repository.Repository, logger *logging.Logger) *Service {
-	return &Service{repo: repo, logger: logger}
+func NewService(repo repository.PersonRepository, logger *logging.Logger) *Service {
+	return &Service{
+		repo:           repo,
+		logger:         logger,
+		imports:        &importJobs{jobs: make(map[string]*model.ImportJob)},
+		background:     context.Background(),
+		events:         &personEvents{subscribers: make(map[chan PersonEvent]struct{})},
+		agifyURL:       "https://api.agify.io",
+		genderizeURL:   "https://api.genderize.io",
+		nationalizeURL: "https://api.nationalize.io",
+	}
+}
+
+// SetBackgroundContext задает контекст фоновых задач сервиса, например импорта. Его отмена прерывает задачи,
+// поэтому при остановке приложения они не продолжают писать в закрываемое хранилище.
+func (s *Service) SetBackgroundContext(ctx context.Context) {
+	s.background = ctx
 }
 
 // CreatePerson создает новую запись о человеке в базе данных.
 // Обогащает данные о возрасте, поле и национальности с использованием внешних сервисов Agify, Genderize и Nationalize.
-func (s *Service) CreatePerson(person *model.Person) error {
+// Возвращает ErrValidation для некорректных данных и ErrUpstreamUnavailable, если внешний сервис недоступен.
+func (s *Service) CreatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error {
 	s.logger.Debug("Service: Handling CreatePerson request")
 
-	age, err := s.enrichWithAge(person.Name)
+	// Поля клиента проверяются до обогащения, чтобы не обращаться к внешним сервисам с некорректными данными.
+	if err := person.ValidateInput(); err != nil {
+		s.logger.Warn("Invalid person:", err)
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	age, err := s.enrichWithAge(ctx, person.Name)
 	if err != nil {
 		s.logger.Errorf("Failed to enrich with age: %v", err)
-		return err //errors.New("failed to enrich with age")
+		return upstreamError("Agify", err)
 	}
-	gender, err := s.enrichWithGender(person.Name)
+	gender, err := s.enrichWithGender(ctx, person.Name)
 	if err != nil {
 		s.logger.Errorf("Failed to enrich with gender: %v", err)
-		return err //errors.New("failed to enrich with gender")
+		return upstreamError("Genderize", err)
 	}
-	nationality, err := s.enrichWithNationality(person.Name)
+	nationality, err := s.enrichWithNationality(ctx, person.Name)
 	if err != nil {
 		s.logger.Errorf("Failed to enrich with nationality: %v", err)
-		return err //errors.New("failed to enrich with nationality")
+		return upstreamError("Nationalize", err)
 	}
 
 	person.Age = age
 	person.Gender = gender
 	person.Nationality = nationality
+	enrichedAt := time.Now().UTC()
+	person.EnrichedAt = &enrichedAt
+
+	// Данные обогащения тоже должны помещаться в колонки.
+	if err := person.ValidateEnrichment(); err != nil {
+		s.logger.Warn("Invalid person enrichment:", err)
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	if err := s.repo.CreatePerson(ctx, person, audit); err != nil {
+		return storageError(err, "person", "create person")
+	}
+	s.publish(model.OperationCreate, *person)
+	return nil
+}
 
-	return s.repo.CreatePerson(person)
+// ImportPeople создает людей из импортируемых данных без обогащения внешними сервисами.
+// Некорректные строки не создаются и возвращаются в ошибках результата с индексами строк people.
+func (s *Service) ImportPeople(ctx context.Context, people []model.Person, audit model.AuditInfo) (*model.BulkCreateResult, error) {
+	s.logger.Debugf("Service: Handling ImportPeople request with %d rows", len(people))
 
+	result, err := s.repo.BulkCreatePeople(ctx, people, audit)
+	if err != nil {
+		return nil, storageError(err, "people", "import people")
+	}
+	return result, nil
 }
 
 // GetPeople возвращает список людей с учетом переданных фильтров, смещения и лимита.
+// Удаленные записи доступны только администратору, иначе возвращается ErrForbidden.
 // Возрашаеть ошибку если не удолась.
-func (s *Service) GetPeople(filter map[string]interface{}, offset, limit int) ([]model.Person, error) {
+func (s *Service) GetPeople(ctx context.Context, filter model.PersonFilter) ([]model.Person, error) {
 	s.logger.Debug("Service: Handling GetPeople request")
 
-	people, err := s.repo.GetPeople(filter, offset, limit)
+	if filter.IncludeDeleted {
+		if err := s.requireAdmin(ctx, "get deleted people"); err != nil {
+			return nil, err
+		}
+	}
+
+	people, err := s.repo.GetPeople(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, storageError(err, "people", "get people")
 	}
 	return people, nil
 }
 
+// ExportPeople вызывает fn для каждого человека, подходящего под фильтры и сортировку filter, без смещения и лимита.
+// Люди читаются из хранилища потоком, ошибка fn прерывает выгрузку. Удаленные записи, как и в GetPeople,
+// доступны только администратору.
+func (s *Service) ExportPeople(ctx context.Context, filter model.PersonFilter, fn func(person *model.Person) error) error {
+	s.logger.Debug("Service: Handling ExportPeople request")
+
+	if filter.IncludeDeleted {
+		if err := s.requireAdmin(ctx, "export deleted people"); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.ExportPeople(ctx, filter, fn); err != nil {
+		return storageError(err, "people", "export people")
+	}
+	return nil
+}
+
 // GetPersonById возвращает информацию о человеке по его идентификатору.
-// Возвращает ошибку, если человек не найден или при возникновении других проблем.
-func (s *Service) GetPersonById(id int) (*model.Person, error) {
+// Возвращает ErrNotFound, если человек не найден, или ошибку при возникновении других проблем.
+func (s *Service) GetPersonById(ctx context.Context, id int) (*model.Person, error) {
 	s.logger.Debug("Service: Handling GetPersonById request")
 
-	person, err := s.repo.GetPersonById(id)
+	person, err := s.repo.GetPersonById(ctx, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			s.logger.Warn("Person not found:", err)
-			return nil, errors.New("person not found")
-		}
-		s.logger.Error("Failed to get person:", err)
-		return nil, errors.New("failed to get person")
-
+		return nil, storageError(err, "person", "get person")
 	}
 	return person, nil
 }
 
 // UpdatePerson обновляет информацию о человеке в базе данных.
-// Возвращает ошибку, если не удалось обновить информацию или при возникновении других проблем
-func (s *Service) UpdatePerson(person *model.Person) error {
+// Если person.Version не равна нулю, возвращает ErrVersionMismatch при несовпадении с текущей версией.
+// Возвращает ErrValidation для некорректных данных, ErrNotFound, если человек не найден,
+// или ошибку при возникновении других проблем.
+func (s *Service) UpdatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error {
 	s.logger.Debug("Service: Handling UpdatePerson request")
 
-	err := s.repo.UpdatePerson(person)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			s.logger.Warn("Person not found:", err)
-			return errors.New("person not found")
-		}
-		s.logger.Error("Failed to update person:", err)
-		return errors.New("failed to update person")
+	if err := person.Validate(); err != nil {
+		s.logger.Warn("Invalid person:", err)
+		return fmt.Errorf("%w: %w", ErrValidation, err)
 	}
+
+	if err := s.repo.UpdatePerson(ctx, person, audit); err != nil {
+		return storageError(err, "person", "update person")
+	}
+	s.publish(model.OperationUpdate, *person)
 	return nil
 }
 
+// PatchPerson применяет patch к текущему состоянию человека и сохраняет только изменившиеся поля.
+// Если version не равна нулю, возвращает ErrVersionMismatch при несовпадении с текущей версией,
+// иначе патч повторно применяется к свежему состоянию, если запись успели изменить параллельно.
+// Ошибка patch возвращается без изменений, чтобы вызывающий код мог отличить некорректный патч,
+// некорректный результат патча возвращает ErrValidation.
+func (s *Service) PatchPerson(ctx context.Context, id, version int, patch func(person *model.Person) error, audit model.AuditInfo) (*model.Person, error) {
+	s.logger.Debug("Service: Handling PatchPerson request")
+
+	const maxRetries = 3
+
+	// Текущее состояние читается с основной базы данных: на отстающей реплике версия может быть устаревшей.
+	readCtx := WithStrongConsistency(ctx)
+
+	for retry := 0; ; retry++ {
+		// Чтение и запись выполняются одной транзакцией с настройками изоляции и повторов хранилища.
+		var patched model.Person
+		err := s.inTx(ctx, "patch person", func(repo repository.PersonRepository) error {
+			current, err := repo.GetPersonById(readCtx, id)
+			if err != nil {
+				return storageError(err, "person", "get person")
+			}
+			if version != 0 && current.Version != version {
+				s.logger.Warnf("Person version mismatch: expected %d, got %d", version, current.Version)
+				return ErrVersionMismatch
+			}
+
+			patched = *current
+			if err := patch(&patched); err != nil {
+				return err
+			}
+			patched.ID = current.ID
+			if err := patched.Validate(); err != nil {
+				s.logger.Warn("Invalid patched person:", err)
+				return fmt.Errorf("%w: %w", ErrValidation, err)
+			}
+
+			patched.Version, err = repo.PatchPerson(ctx, id, current.Version, changedFields(current, &patched), audit)
+			if err != nil {
+				return storageError(err, "person", "patch person")
+			}
+			return nil
+		})
+		if err == nil {
+			s.publish(model.OperationUpdate, patched)
+			return &patched, nil
+		}
+		// При version == 0 ErrVersionMismatch означает, что запись изменили параллельно между чтением и записью,
+		// например на уровне read committed: изменение повторяется на свежем состоянии.
+		if errors.Is(err, ErrVersionMismatch) && version == 0 && retry+1 < maxRetries {
+			continue
+		}
+		return nil, err
+	}
+}
+
+// inTx выполняет fn одной транзакцией хранилища, если оно поддерживает транзакции, и без транзакции иначе.
+// fn возвращает ошибки сервиса и может выполняться повторно, конфликт транзакций переводится в ErrConflict
+// с описанием действия action.
+func (s *Service) inTx(ctx context.Context, action string, fn func(repo repository.PersonRepository) error) error {
+	transactor, ok := s.repo.(repository.Transactor)
+	if !ok {
+		return fn(s.repo)
+	}
+	err := transactor.WithTx(ctx, func(tx repository.Storage) error {
+		return fn(tx)
+	})
+	if errors.Is(err, repository.ErrTxConflict) {
+		return storageError(err, "person", action)
+	}
+	return err
+}
+
+// changedFields возвращает колонки people, значения которых отличаются в before и after.
+func changedFields(before, after *model.Person) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if before.Name != after.Name {
+		fields["name"] = after.Name
+	}
+	if before.Surname != after.Surname {
+		fields["surname"] = after.Surname
+	}
+	if before.Patronymic != after.Patronymic {
+		fields["patronymic"] = after.Patronymic
+	}
+	if before.Age != after.Age {
+		fields["age"] = after.Age
+	}
+	if before.Gender != after.Gender {
+		fields["gender"] = after.Gender
+	}
+	if before.Nationality != after.Nationality {
+		fields["nationality"] = after.Nationality
+	}
+	return fields
+}
+
 // DeletePerson удаляет запись о человеке из базы данных по его id.
-func (s *Service) DeletePerson(id int) error {
+// Если version не равна нулю, возвращает ErrVersionMismatch при несовпадении с текущей версией.
+func (s *Service) DeletePerson(ctx context.Context, id, version int, audit model.AuditInfo) error {
 	s.logger.Debug("Service: Handling DeletePerson request")
 
-	err := s.repo.DeletePerson(id)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			s.logger.Warn("person not found:", err)
-			return errors.New("person not found")
-		}
-		s.logger.Error("Failed to delete person:", err)
-		return errors.New("failed to delete person")
+	if err := s.repo.DeletePerson(ctx, id, version, audit); err != nil {
+		return storageError(err, "person", "delete person")
 	}
+	s.publish(model.OperationDelete, model.Person{ID: uint(id)})
 	return nil
 }
 
+// RestorePerson восстанавливает удаленную запись о человеке и возвращает ее. Восстановление доступно только
+// администратору, иначе возвращается ErrForbidden.
+// Возвращает ErrNotFound, если удаленный человек не найден, или ошибку при возникновении других проблем.
+func (s *Service) RestorePerson(ctx context.Context, id int, audit model.AuditInfo) (*model.Person, error) {
+	s.logger.Debug("Service: Handling RestorePerson request")
+
+	if err := s.requireAdmin(ctx, "restore person"); err != nil {
+		return nil, err
+	}
+
+	// Восстановление и его запись в историю выполняются одной транзакцией с настройками изоляции и повторов хранилища.
+	var person *model.Person
+	err := s.inTx(ctx, "restore person", func(repo repository.PersonRepository) error {
+		restored, err := repo.RestorePerson(ctx, id, audit)
+		if err != nil {
+			return storageError(err, "deleted person", "restore person")
+		}
+		person = restored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publish(model.OperationRestore, *person)
+	return person, nil
+}
+
+// PurgeDeletedPeople окончательно удаляет записи, удаленные раньше, чем retention назад.
+// В истории изменений удаление записывается от имени SystemActor. Возвращает количество удаленных записей.
+func (s *Service) PurgeDeletedPeople(ctx context.Context, retention time.Duration) (int64, error) {
+	s.logger.Debug("Service: Handling PurgeDeletedPeople request")
+
+	purged, err := s.repo.PurgeDeletedPeople(ctx, time.Now().Add(-retention), model.AuditInfo{Actor: SystemActor})
+	if err != nil {
+		return 0, storageError(err, "people", "purge deleted people")
+	}
+	return purged, nil
+}
+
+// GetPersonHistory возвращает историю изменений человека в хронологическом порядке.
+// История доступна и для удаленных записей. Возвращает ErrNotFound, если у человека нет истории.
+func (s *Service) GetPersonHistory(ctx context.Context, id int) ([]model.PersonChange, error) {
+	s.logger.Debug("Service: Handling GetPersonHistory request")
+
+	changes, err := s.repo.GetPersonHistory(ctx, id)
+	if err != nil {
+		return nil, storageError(err, "person", "get person history")
+	}
+	if len(changes) == 0 {
+		return nil, storageError(sql.ErrNoRows, "person", "get person history")
+	}
+	return changes, nil
+}
+
+// GetPersonAsOf восстанавливает состояние человека на момент asOf по истории изменений.
+// Возвращает ErrNotFound, если в этот момент записи не было или она была удалена.
+func (s *Service) GetPersonAsOf(ctx context.Context, id int, asOf time.Time) (*model.Person, error) {
+	s.logger.Debug("Service: Handling GetPersonAsOf request")
+
+	change, err := s.repo.GetPersonChangeAsOf(ctx, id, asOf)
+	if err != nil {
+		return nil, storageError(err, "person", "get person history")
+	}
+
+	person, err := repository.UnmarshalPersonSnapshot(change.After)
+	if err != nil {
+		s.logger.Error("Failed to parse person history:", err)
+		return nil, fmt.Errorf("failed to parse person history: %w", err)
+	}
+	if person == nil || person.DeletedAt != nil {
+		s.logger.Warnf("Person %d was deleted as of %s", id, asOf)
+		return nil, fmt.Errorf("person %w", ErrNotFound)
+	}
+	return person, nil
+}
+
 // enrichWithAge обогащает данные возрастом,
 // подробнее: сразу не сдается при проблемах с внешним сервисом,
 // а предпринимает попытки восстановления это делают код более устойчивым к временным проблемам с внешним сервисом.
-func (s *Service) enrichWithAge(name string) (int, error) {
+func (s *Service) enrichWithAge(ctx context.Context, name string) (int, error) {
 	s.logger.Debug("Service: Enriching with age")
 
 	const maxRetries = 3
 	var age int
 
 	for retry := 0; retry < maxRetries; retry++ {
-		url := fmt.Sprintf("https://api.agify.io/?name=%s", name)
-		resp, err := http.Get(url)
+		url := fmt.Sprintf("%s/?name=%s", s.agifyURL, name)
+		resp, err := getWithContext(ctx, url)
 		if err != nil {
 			s.logger.Errorf("Failed to get age from Agify: %v", err)
-			time.Sleep(time.Second) // Пауза перед повторной попыткой
+			if err := sleepContext(ctx, time.Second); err != nil { // Пауза перед повторной попыткой
+				return 0, err
+			}
 			continue
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			s.logger.Errorf("Failed to get age from Agify. Status code: %d", resp.StatusCode)
-			time.Sleep(time.Second) // Пауза перед повторной попыткой
+			if err := sleepContext(ctx, time.Second); err != nil { // Пауза перед повторной попыткой
+				return 0, err
+			}
 			continue
 		}
 
 		if resp.StatusCode != http.StatusOK {
 			s.logger.Errorf("Attempt %d: Unexpected status code from Agify: %d", retry+1, resp.StatusCode)
-			time.Sleep(time.Second) // Пауза перед повторной попыткой
+			if err := sleepContext(ctx, time.Second); err != nil { // Пауза перед повторной попыткой
+				return 0, err
+			}
 			continue
 		}
 
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
 			s.logger.Errorf("Attempt %d: Failed to read Agify response body: %v", retry+1, err)
-			time.Sleep(time.Second) // Пауза перед повторной попыткой
+			if err := sleepContext(ctx, time.Second); err != nil { // Пауза перед повторной попыткой
+				return 0, err
+			}
 			continue
 		}
 		var result map[string]interface{}
 		if err := json.Unmarshal(body, &result); err != nil {
 			s.logger.Errorf("Attempt %d: Failed to parse Agify response: %v", retry+1, err)
-			time.Sleep(time.Second) // Пауза перед повторной попыткой
+			if err := sleepContext(ctx, time.Second); err != nil { // Пауза перед повторной попыткой
+				return 0, err
+			}
 			continue
 		}
 		age, ok := result["age"].(float64)
@@ -172,11 +437,11 @@ func (s *Service) enrichWithAge(name string) (int, error) {
 
 // enrichWithGender обогащает данные полом с использованием внешнего сервиса Genderize.
 // Возвращает пол и ошибку, если запрос к сервису не удался.
-func (s *Service) enrichWithGender(name string) (string, error) {
+func (s *Service) enrichWithGender(ctx context.Context, name string) (string, error) {
 	s.logger.Debug("Service: Enriching with gender")
 
-	url := fmt.Sprintf("https://api.genderize.io/?name=%s", name)
-	resp, err := http.Get(url)
+	url := fmt.Sprintf("%s/?name=%s", s.genderizeURL, name)
+	resp, err := getWithContext(ctx, url)
 	if err != nil {
 		s.logger.Errorf("Failed to get gender from Genderize: %v", err)
 		return "", err
@@ -208,11 +473,11 @@ func (s *Service) enrichWithGender(name string) (string, error) {
 
 // enrichWithNationality обогащает данные национальностью с использованием внешнего сервиса Nationalize.
 // Возвращает национальность и ошибку, если запрос к сервису не удался.
-func (s *Service) enrichWithNationality(name string) (string, error) {
+func (s *Service) enrichWithNationality(ctx context.Context, name string) (string, error) {
 	s.logger.Debug("Service: Enriching with nationality")
 
-	url := fmt.Sprintf("https://api.nationalize.io/?name=%s", name)
-	resp, err := http.Get(url)
+	url := fmt.Sprintf("%s/?name=%s", s.nationalizeURL, name)
+	resp, err := getWithContext(ctx, url)
 	if err != nil {
 		s.logger.Errorf("Failed to get nationality from Nationalize: %v", err)
 		return "", err
@@ -259,3 +524,24 @@ func (s *Service) enrichWithNationality(name string) (string, error) {
 	return nationality, nil
 
 }
+
+// getWithContext выполняет GET-запрос к внешнему сервису, который отменяется вместе с ctx.
+func getWithContext(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// sleepContext ждет d или отмены ctx, в последнем случае возвращает ошибку ctx.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
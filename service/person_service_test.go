@@ -1,10 +1,17 @@
 package service
 
 import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testProject/internal/model"
 	"testProject/pkg/logging"
 	"testProject/repository"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -13,36 +20,101 @@ type MockRepository struct {
 	mock.Mock
 }
 
-type MockLogger struct {
-	mock.Mock
+func (m *MockRepository) CreatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error {
+	args := m.Called(person, audit)
+	return args.Error(0)
 }
 
-func (m *MockRepository) CreatePerson(person *model.Person) error {
-	args := m.Called(person)
+func (m *MockRepository) BulkCreatePeople(ctx context.Context, people []model.Person, audit model.AuditInfo) (*model.BulkCreateResult, error) {
+	args := m.Called(people, audit)
+	result, _ := args.Get(0).(*model.BulkCreateResult)
+	return result, args.Error(1)
+}
+
+func (m *MockRepository) GetPeople(ctx context.Context, filter model.PersonFilter) ([]model.Person, error) {
+	args := m.Called(filter)
+	people, _ := args.Get(0).([]model.Person)
+	return people, args.Error(1)
+}
+
+func (m *MockRepository) ExportPeople(ctx context.Context, filter model.PersonFilter, fn func(person *model.Person) error) error {
+	args := m.Called(filter)
 	return args.Error(0)
 }
 
-func (m *MockLogger) Logger(message string) {
-	m.Called(message)
+func (m *MockRepository) GetPersonById(ctx context.Context, id int) (*model.Person, error) {
+	args := m.Called(id)
+	person, _ := args.Get(0).(*model.Person)
+	return person, args.Error(1)
 }
 
-func TestCreatePerson(t *testing.T) {
-	repo := new(MockRepository)
-	logger := new(MockLogger)
+func (m *MockRepository) UpdatePerson(ctx context.Context, person *model.Person, audit model.AuditInfo) error {
+	args := m.Called(person, audit)
+	return args.Error(0)
+}
 
-	repoWrapper := struct {
-		repository.Repository
-	}{
-		Repository: repository.Repository{},
-	}
+func (m *MockRepository) PatchPerson(ctx context.Context, id, version int, fields map[string]interface{}, audit model.AuditInfo) (int, error) {
+	args := m.Called(id, version, fields, audit)
+	return args.Int(0), args.Error(1)
+}
 
-	loggerWrapper := struct {
-		logging.Logger
-	}{
-		Logger: logging.Logger{},
-	}
+func (m *MockRepository) DeletePerson(ctx context.Context, id, version int, audit model.AuditInfo) error {
+	args := m.Called(id, version, audit)
+	return args.Error(0)
+}
 
-	service := NewService(&repoWrapper.Repository, &loggerWrapper.Logger)
+func (m *MockRepository) RestorePerson(ctx context.Context, id int, audit model.AuditInfo) (*model.Person, error) {
+	args := m.Called(id, audit)
+	person, _ := args.Get(0).(*model.Person)
+	return person, args.Error(1)
+}
+
+func (m *MockRepository) PurgeDeletedPeople(ctx context.Context, deletedBefore time.Time, audit model.AuditInfo) (int64, error) {
+	args := m.Called(deletedBefore, audit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) GetPersonHistory(ctx context.Context, personID int) ([]model.PersonChange, error) {
+	args := m.Called(personID)
+	changes, _ := args.Get(0).([]model.PersonChange)
+	return changes, args.Error(1)
+}
+
+func (m *MockRepository) GetPersonChangeAsOf(ctx context.Context, personID int, asOf time.Time) (*model.PersonChange, error) {
+	args := m.Called(personID, asOf)
+	change, _ := args.Get(0).(*model.PersonChange)
+	return change, args.Error(1)
+}
+
+// newEnrichmentServer имитирует Agify, Genderize и Nationalize.
+func newEnrichmentServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/agify/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"TestName","age":22}`))
+	})
+	mux.HandleFunc("/genderize/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"TestName","gender":"male"}`))
+	})
+	mux.HandleFunc("/nationalize/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"TestName","country":[{"country_id":"RU","probability":0.5}]}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestService(t *testing.T, repo *MockRepository) *Service {
+	server := newEnrichmentServer(t)
+	service := NewService(repo, logging.GetLogger())
+	service.agifyURL = server.URL + "/agify"
+	service.genderizeURL = server.URL + "/genderize"
+	service.nationalizeURL = server.URL + "/nationalize"
+	return service
+}
+
+func TestCreatePerson(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
 
 	testPerson := &model.Person{
 		ID:          1,
@@ -53,16 +125,300 @@ func TestCreatePerson(t *testing.T) {
 		Gender:      "ManGender",
 		Nationality: "TestNationality",
 	}
+	audit := model.AuditInfo{Actor: "tester", RequestID: "request-1"}
 
-	repo.On("CreatePerson", testPerson).Return(nil)
+	repo.On("CreatePerson", testPerson, audit).Return(nil)
 
-	logger.On("Debug", mock.Anything)
-
-	err := service.CreatePerson(testPerson)
+	err := service.CreatePerson(context.Background(), testPerson, audit)
 	if err != nil {
 		t.Errorf("Expected no error, but got %v", err)
 	}
+	if testPerson.Age != 22 || testPerson.Gender != "male" || testPerson.Nationality != "RU" {
+		t.Errorf("Expected person to be enriched, got %+v", testPerson)
+	}
+
+	repo.AssertExpectations(t)
+}
+
+func TestPatchPersonVersionMismatch(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	repo.On("GetPersonById", 1).Return(&model.Person{ID: 1, Name: "TestName", Version: 3}, nil)
+
+	_, err := service.PatchPerson(context.Background(), 1, 2, func(person *model.Person) error {
+		person.Surname = "Changed"
+		return nil
+	}, model.AuditInfo{})
+	if err != ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch, but got %v", err)
+	}
+
+	repo.AssertNotCalled(t, "PatchPerson", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+}
+
+func TestPatchPersonInTx(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository(logging.GetLogger())
+	service := NewService(repo, logging.GetLogger())
+	person := &model.Person{Name: "Ivan", Surname: "Ivanov", Age: 30}
+	if err := repo.CreatePerson(ctx, person, model.AuditInfo{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	calls := 0
+	patched, err := service.PatchPerson(ctx, int(person.ID), 0, func(p *model.Person) error {
+		calls++
+		if calls == 1 {
+			// Параллельное изменение после чтения: транзакция выполняется заново на свежем состоянии.
+			if _, err := repo.PatchPerson(ctx, int(person.ID), 1, map[string]interface{}{"age": 31}, model.AuditInfo{}); err != nil {
+				return err
+			}
+		}
+		p.Surname = "Petrov"
+		return nil
+	}, model.AuditInfo{})
+	if err != nil || calls != 2 {
+		t.Fatalf("Expected patch to succeed on the second attempt, got %d calls and %v", calls, err)
+	}
+	if patched.Age != 31 || patched.Surname != "Petrov" || patched.Version != 3 {
+		t.Errorf("Expected both changes to be kept, got %+v", patched)
+	}
+}
+
+func TestCreatePersonCanceled(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := service.CreatePerson(ctx, &model.Person{Name: "TestName", Surname: "TestSurname"}, model.AuditInfo{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, but got %v", err)
+	}
+
+	repo.AssertNotCalled(t, "CreatePerson", mock.Anything, mock.Anything)
+}
+
+func TestCreatePersonValidatesBeforeEnrichment(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	service.agifyURL, service.genderizeURL, service.nationalizeURL = server.URL, server.URL, server.URL
+
+	err := service.CreatePerson(context.Background(), &model.Person{Name: "Ivan2", Surname: "Ivanov"}, model.AuditInfo{})
+	var fieldErrs model.ValidationErrors
+	if !errors.Is(err, ErrValidation) || !errors.As(err, &fieldErrs) || fieldErrs[0].Field != "name" {
+		t.Errorf("Expected name validation error, but got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no enrichment requests, but got %d", requests)
+	}
+	repo.AssertNotCalled(t, "CreatePerson", mock.Anything, mock.Anything)
+}
+
+func TestCreatePersonInvalidEnrichment(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"age":200,"gender":"male","country":[{"country_id":"RU"}]}`))
+	}))
+	defer server.Close()
+	service.agifyURL = server.URL
+
+	err := service.CreatePerson(context.Background(), &model.Person{Name: "Ivan", Surname: "Ivanov"}, model.AuditInfo{})
+	var fieldErrs model.ValidationErrors
+	if !errors.Is(err, ErrValidation) || !errors.As(err, &fieldErrs) || fieldErrs[0].Field != "age" {
+		t.Errorf("Expected age validation error, but got %v", err)
+	}
+	repo.AssertNotCalled(t, "CreatePerson", mock.Anything, mock.Anything)
+}
+
+func TestServiceErrors(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	err := service.CreatePerson(context.Background(), &model.Person{Name: "TestName"}, model.AuditInfo{})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation, but got %v", err)
+	}
+
+	repo.On("GetPersonById", 999).Return(nil, sql.ErrNoRows)
+	if _, err := service.GetPersonById(context.Background(), 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, but got %v", err)
+	}
+
+	repo.On("DeletePerson", 1, 0, model.AuditInfo{}).Return(repository.ErrTxConflict)
+	if err := service.DeletePerson(context.Background(), 1, 0, model.AuditInfo{}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict, but got %v", err)
+	}
+
+	service.genderizeURL = "http://127.0.0.1:0"
+	err = service.CreatePerson(context.Background(), &model.Person{Name: "TestName", Surname: "TestSurname"}, model.AuditInfo{})
+	if !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Errorf("Expected ErrUpstreamUnavailable, but got %v", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	repo.On("DeletePerson", 1, 0, mock.Anything).Return(nil)
+	repo.On("DeletePerson", 2, 0, mock.Anything).Return(sql.ErrNoRows)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	events := service.Subscribe(ctx)
+
+	if err := service.DeletePerson(context.Background(), 2, 0, model.AuditInfo{}); err == nil {
+		t.Fatal("Expected error, but got nil")
+	}
+	if err := service.DeletePerson(context.Background(), 1, 0, model.AuditInfo{}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	// Неудачные изменения событий не создают.
+	if event := <-events; event.Operation != model.OperationDelete || event.Person.ID != 1 {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("Expected events to be closed after cancel")
+	}
+
+	// Подписка, которая не читает события, закрывается, не блокируя изменения.
+	slow := service.Subscribe(context.Background())
+	for i := 0; i <= eventBuffer; i++ {
+		if err := service.DeletePerson(context.Background(), 1, 0, model.AuditInfo{}); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+	}
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != eventBuffer {
+		t.Errorf("Expected %d events before close, but got %d", eventBuffer, received)
+	}
+}
+
+// readerFunc реализует importer.Reader функцией.
+type readerFunc func() (model.Person, error)
+
+func (f readerFunc) Read() (model.Person, error) {
+	return f()
+}
+
+func TestImportErrors(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	// Поврежденный файл - ошибка данных клиента, а не внутренняя ошибка.
+	broken := readerFunc(func() (model.Person, error) { return model.Person{}, bufio.ErrTooLong })
+	_, err := service.Import(context.Background(), broken, ImportOptions{}, model.AuditInfo{}, nil)
+	if !errors.Is(err, ErrValidation) || !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("Expected ErrValidation wrapping the read error, but got %v", err)
+	}
+
+	// Фоновый импорт прерывается отменой фонового контекста сервиса.
+	ctx, cancel := context.WithCancel(context.Background())
+	service.SetBackgroundContext(ctx)
+	endless := readerFunc(func() (model.Person, error) {
+		cancel()
+		return model.Person{Name: "Ivan", Surname: "Ivanov"}, nil
+	})
+	job, err := service.StartImportJob(endless, ImportOptions{}, model.AuditInfo{}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); job.Status == model.ImportJobRunning && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if job, err = service.GetImportJob(job.ID); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+	}
+	if job.Status != model.ImportJobFailed || job.Error != context.Canceled.Error() {
+		t.Errorf("Expected job canceled with the background context, got %+v", job)
+	}
+	repo.AssertNotCalled(t, "BulkCreatePeople", mock.Anything, mock.Anything)
+}
+
+func TestDeletedPeopleAccess(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	filter := model.PersonFilter{IncludeDeleted: true, Limit: 10}
+	if _, err := service.GetPeople(context.Background(), filter); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden, but got %v", err)
+	}
+	if _, err := service.RestorePerson(context.Background(), 1, model.AuditInfo{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden, but got %v", err)
+	}
+	repo.AssertNotCalled(t, "GetPeople", mock.Anything)
+	repo.AssertNotCalled(t, "RestorePerson", mock.Anything, mock.Anything)
+
+	ctx := AuthorizeAdmin(context.Background(), "Bearer secret", "secret")
+	repo.On("GetPeople", filter).Return([]model.Person{{ID: 1}}, nil)
+	if people, err := service.GetPeople(ctx, filter); err != nil || len(people) != 1 {
+		t.Errorf("Expected deleted people for admin, got %v %v", people, err)
+	}
+	for _, authorization := range []string{"", "secret", "Bearer wrong"} {
+		if IsAdmin(AuthorizeAdmin(context.Background(), authorization, "secret")) {
+			t.Errorf("Expected %q not to authorize admin", authorization)
+		}
+	}
+	if IsAdmin(AuthorizeAdmin(context.Background(), "Bearer ", "")) {
+		t.Error("Expected empty token to disable admin access")
+	}
+}
+
+func TestPurgeDeletedPeople(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	retention := 30 * 24 * time.Hour
+	before := time.Now().Add(-retention)
+	repo.On("PurgeDeletedPeople", mock.MatchedBy(func(deletedBefore time.Time) bool {
+		// Удаляются только записи, удаленные раньше, чем retention назад.
+		return !deletedBefore.Before(before) && deletedBefore.Before(time.Now().Add(-retention+time.Minute))
+	}), model.AuditInfo{Actor: SystemActor}).Return(int64(2), nil)
+
+	purged, err := service.PurgeDeletedPeople(context.Background(), retention)
+	if err != nil || purged != 2 {
+		t.Errorf("Expected 2 purged people, got %d and %v", purged, err)
+	}
 	repo.AssertExpectations(t)
-	logger.AssertExpectations(t)
+}
+
+func TestGetPeoplePage(t *testing.T) {
+	repo := new(MockRepository)
+	service := newTestService(t, repo)
+
+	repo.On("GetPeople", model.PersonFilter{Offset: 2, Limit: 3}).Return([]model.Person{{ID: 3}, {ID: 4}, {ID: 5}}, nil).Once()
+	page, err := service.GetPeoplePage(context.Background(), model.PersonFilter{Offset: 2, Limit: 2})
+	if err != nil || len(page.People) != 2 || !page.HasNext {
+		t.Errorf("Expected 2 people and a next page, got %+v and %v", page, err)
+	}
+	repo.On("GetPeople", model.PersonFilter{Offset: 4, Limit: 3}).Return([]model.Person{{ID: 5}}, nil).Once()
+	page, err = service.GetPeoplePage(context.Background(), model.PersonFilter{Offset: 4, Limit: 2})
+	if err != nil || len(page.People) != 1 || page.HasNext {
+		t.Errorf("Expected the last page, got %+v and %v", page, err)
+	}
+
+	if offset, err := DecodeCursor(EncodeCursor(42)); err != nil || offset != 42 {
+		t.Errorf("Expected offset 42, got %d and %v", offset, err)
+	}
+	for _, cursor := range []string{"", "not base64!", EncodeCursor(-1), "b2Zmc2V0OmFiYw=="} {
+		if _, err := DecodeCursor(cursor); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation for cursor %q, got %v", cursor, err)
+		}
+	}
 }